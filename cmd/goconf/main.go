@@ -0,0 +1,120 @@
+// Command goconf is a tool for goconf configuration files.
+//
+// Usage:
+//
+//	goconf fmt [flags] [path ...]
+//
+// Without paths fmt reads the standard input and writes the formatted
+// config to the standard output. The flags are:
+//
+//	-l
+//		list files whose formatting differs from goconf's
+//	-w
+//		write the result to the source file instead of the standard output
+//	-s
+//		sort the keys of every section
+//	-a
+//		align the values of every section (default true)
+//	-comment string
+//		comment marker (default "#")
+//	-spliter string
+//		key-value separator (default " ")
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Terry-Mao/goconf"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: goconf fmt [flags] [path ...]\n")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "fmt" {
+		usage()
+	}
+	os.Exit(fmtMain(os.Args[2:]))
+}
+
+func fmtMain(args []string) int {
+	var (
+		opts  goconf.FormatOptions
+		fs    = flag.NewFlagSet("fmt", flag.ExitOnError)
+		list  = fs.Bool("l", false, "list files whose formatting differs from goconf's")
+		write = fs.Bool("w", false, "write result to (source) file instead of stdout")
+		code  = 0
+	)
+	fs.BoolVar(&opts.SortKeys, "s", false, "sort the keys of every section")
+	fs.BoolVar(&opts.Align, "a", true, "align the values of every section")
+	fs.StringVar(&opts.Comment, "comment", goconf.Comment, "comment marker")
+	fs.StringVar(&opts.Spliter, "spliter", goconf.Spliter, "key-value separator")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: goconf fmt [flags] [path ...]\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		if *write {
+			fmt.Fprintf(os.Stderr, "goconf: cannot use -w with standard input\n")
+			return 2
+		}
+		if err := processFile("<standard input>", os.Stdin, opts, *list); err != nil {
+			fmt.Fprintf(os.Stderr, "goconf: %s\n", err)
+			return 2
+		}
+		return 0
+	}
+	for _, path := range fs.Args() {
+		if *write {
+			// atomic and locked like goconf.Config.Save
+			changed, err := goconf.FormatFile(path, opts)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "goconf: %s: %s\n", path, err)
+				code = 2
+			} else if *list && changed {
+				fmt.Println(path)
+			}
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "goconf: %s\n", err)
+			code = 2
+			continue
+		}
+		err = processFile(path, f, opts, *list)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "goconf: %s: %s\n", path, err)
+			code = 2
+		}
+	}
+	return code
+}
+
+// processFile format one config, list it or write it to the standard output.
+func processFile(path string, r io.Reader, opts goconf.FormatOptions, list bool) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	if err = goconf.Format(bytes.NewReader(src), &out, opts); err != nil {
+		return err
+	}
+	changed := !bytes.Equal(src, out.Bytes())
+	if list && changed {
+		fmt.Println(path)
+	}
+	if !list {
+		_, err = os.Stdout.Write(out.Bytes())
+	}
+	return err
+}
@@ -5,10 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
 	"os"
-	"reflect"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -19,11 +23,32 @@ const (
 	Spliter  = " "
 	SectionS = "["
 	SectionE = "]"
-	// memory unit
+	// layered configs, see Merge
+	DeleteKey = "!"
+	AppendKey = "+"
+	Delim     = ","
+	// backup
+	BackupExt  = ".bak"
+	BackupTime = "20060102T150405.000000000"
+	// memory unit, binary multiples, see Section.MemSize
 	Byte = 1
-	KB   = 1024 * Byte
-	MB   = 1024 * KB
-	GB   = 1024 * MB
+	// KB is 1024 bytes, while MemSize reads "1KB" as 1000.
+	//
+	// Deprecated: Use KiB.
+	KB = 1024 * Byte
+	// MB is 1024 KiB, while MemSize reads "1MB" as 1000 * 1000.
+	//
+	// Deprecated: Use MiB.
+	MB = 1024 * KB
+	// GB is 1024 MiB, while MemSize reads "1GB" as 1000 * 1000 * 1000.
+	//
+	// Deprecated: Use GiB.
+	GB  = 1024 * MB
+	KiB = 1024 * Byte
+	MiB = 1024 * KiB
+	GiB = 1024 * MiB
+	TiB = 1024 * GiB
+	PiB = 1024 * TiB
 )
 
 // Section is the key-value data object.
@@ -31,18 +56,72 @@ type Section struct {
 	data         map[string]string // key:value
 	dataOrder    []string
 	dataComments map[string][]string // key:comments
+	ops          map[string]int      // key:operation for "key +=" and "!key"
+	lines        map[string]int      // key:line in the parsed file
+	conf         *Config             // parsing options
 	Name         string
 	comments     []string
 	Comment      string
 }
 
+// key operations of a layer, see Merge.
+const (
+	opSet = iota
+	opAppend
+	opDelete
+)
+
+// newSection return a new empty section of c.
+func (c *Config) newSection(name string, comments []string) *Section {
+	return &Section{data: map[string]string{}, dataComments: map[string][]string{}, ops: map[string]int{}, lines: map[string]int{}, comments: comments, Comment: c.Comment, Name: name, conf: c}
+}
+
+// config return the Config of the section, s may be nil.
+func (s *Section) config() *Config {
+	if s == nil {
+		return nil
+	}
+	return s.conf
+}
+
 // Config is the key-value configuration object.
 type Config struct {
 	data      map[string]*Section
 	dataOrder []string
+	comments  []string // trailing comments
 	file      string
 	Comment   string
 	Spliter   string
+	// Backups is the number of timestamped copies of the previous file kept
+	// by Save, 0 means no backup.
+	Backups int
+	// LockTimeout is how long Save and Edit wait for the file lock, 0 means
+	// DefaultLockTimeout.
+	LockTimeout time.Duration
+	// DurationUnit is the unit of a duration written as a bare number, 0
+	// means time.Nanosecond.
+	DurationUnit time.Duration
+	// LegacyMemSize makes the SI memory units (k, kb, m, mb...) powers of
+	// 1024 as in earlier versions instead of powers of 1000.
+	LegacyMemSize bool
+	// StrictBool makes unknown boolean words an error instead of false.
+	StrictBool bool
+	// IntLiterals accepts Go integer literals with base prefixes and
+	// underscores, such as "0x1F" or "1_000_000". Note a leading "0" then
+	// means octal.
+	IntLiterals bool
+	// KeepListSpace keeps the spaces around unquoted list elements instead
+	// of trimming them.
+	KeepListSpace bool
+	// Delim is the list delimiter that joins the values of "key += value"
+	// lines, in the same file and by Merge, "" means Delim.
+	Delim string
+	// CollectErrors makes Unmarshal decode every field and return all the
+	// failed ones as Errors instead of stopping at the first.
+	CollectErrors bool
+	// DisallowUnknown makes Unmarshal report the sections and keys that no
+	// struct field asks for, such as a misspelt key.
+	DisallowUnknown bool
 }
 
 // New return a new default Config object (Comment = '#', spliter = ' ').
@@ -51,6 +130,10 @@ func New() *Config {
 }
 
 // ParseReader parse config file by a io.Reader.
+//
+// A "key += value" line appends to the key instead of setting it, "+=" must
+// be a token of its own. With a spliter other than whitespace or one starting
+// with "=", such as ":", "+=" follows the spliter: "key: += value".
 func (c *Config) ParseReader(reader io.Reader) error {
 	var (
 		err      error
@@ -88,7 +171,7 @@ func (c *Config) ParseReader(reader io.Reader) error {
 			// store the section
 			s, ok := c.data[sectionStr]
 			if !ok {
-				s = &Section{data: map[string]string{}, dataComments: map[string][]string{}, comments: comments, Comment: c.Comment, Name: sectionStr}
+				s = c.newSection(sectionStr, comments)
 				c.data[sectionStr] = s
 				c.dataOrder = append(c.dataOrder, sectionStr)
 			} else {
@@ -98,33 +181,77 @@ func (c *Config) ParseReader(reader io.Reader) error {
 			comments = []string{}
 			continue
 		}
+		// deleted key
+		if strings.HasPrefix(row, DeleteKey) {
+			key = strings.TrimSpace(row[len(DeleteKey):])
+			if key == "" || strings.Contains(key, c.Spliter) {
+				return errors.New(fmt.Sprintf("deleted key: %s must not have a value at %d", row, line))
+			}
+			if section == nil {
+				return errors.New(fmt.Sprintf("no section for key: %s at %d", key, line))
+			}
+			if _, ok := section.data[key]; ok || section.ops[key] == opDelete {
+				return errors.New(fmt.Sprintf("section: %s already has key: %s at %d", section.Name, key, line))
+			}
+			section.ops[key] = opDelete
+			section.dataComments[key] = comments
+			section.dataOrder = append(section.dataOrder, key)
+			comments = []string{}
+			continue
+		}
 		// get the spliter index
 		idx = strings.Index(row, c.Spliter)
 		if idx > 0 {
 			// get the key and value
-			key = strings.TrimSpace(row[:idx])
+			key = row[:idx]
 			if len(row) > idx {
 				value = strings.TrimSpace(row[idx+1:])
 			}
 		} else {
 			return errors.New(fmt.Sprintf("no spliter in key: %s at %d", row, line))
 		}
+		// appended value, "key += value", "+=" must be a token of its own
+		op := opSet
+		if appendOp := AppendKey + "="; strings.HasSuffix(key, appendOp) {
+			// "key+= value"
+			key = key[:len(key)-len(appendOp)]
+			op = opAppend
+		} else if strings.HasSuffix(key, AppendKey) && strings.HasPrefix(c.Spliter, "=") {
+			// "key += value" split by "="
+			key = key[:len(key)-len(AppendKey)]
+			op = opAppend
+		} else if value == appendOp || strings.HasPrefix(value, appendOp+" ") || strings.HasPrefix(value, appendOp+"\t") {
+			// "key += value" split by " ", or "key: += value" by another spliter
+			value = strings.TrimSpace(value[len(appendOp):])
+			op = opAppend
+		}
+		key = strings.TrimSpace(key)
 		// check section exists
 		if section == nil {
 			return errors.New(fmt.Sprintf("no section for key: %s at %d", key, line))
 		}
 		// check key already exists
-		if _, ok := section.data[key]; ok {
+		if old, ok := section.data[key]; ok && op == opAppend {
+			section.data[key] = old + c.listDelim() + value
+			comments = []string{}
+			continue
+		} else if ok || section.ops[key] == opDelete {
 			return errors.New(fmt.Sprintf("section: %s already has key: %s at %d", section.Name, key, line))
 		}
 		// save key-value
 		section.data[key] = value
+		section.lines[key] = line
+		if op != opSet {
+			section.ops[key] = op
+		}
 		// save comments for key
 		section.dataComments[key] = comments
 		section.dataOrder = append(section.dataOrder, key)
 		// clean comments
 		comments = []string{}
 	}
+	// keep the comments after the last key
+	c.comments = comments
 	return nil
 }
 
@@ -156,7 +283,7 @@ func (c *Config) Add(section string, comments ...string) *Section {
 				dataComments = append(dataComments, fmt.Sprintf("%s%s", c.Comment, line))
 			}
 		}
-		s = &Section{data: map[string]string{}, Name: section, comments: dataComments, Comment: c.Comment, dataComments: map[string][]string{}}
+		s = c.newSection(section, dataComments)
 		c.data[section] = s
 		c.dataOrder = append(c.dataOrder, section)
 	}
@@ -187,57 +314,294 @@ func (c *Config) Sections() []string {
 }
 
 // Save save current configuration to specified file, if file is "" then rewrite the original file.
+//
+// The configuration is written to a temporary file in the same directory
+// which is synced and then renamed over the target, so a crash never leaves
+// a half-written file behind. The mode and owner of an existing file are kept.
+// If file is a symlink the file it points to is replaced and its backups are
+// kept next to it.
+// An advisory lock on the sidecar file "file.lock" is held while saving, use
+// Edit to also protect the read-modify-write cycle. The sidecar is left in
+// place after saving, removing it could let two processes lock different
+// files. Save returns ErrLockUnsupported on platforms without advisory file
+// locking, such as Windows.
 func (c *Config) Save(file string) error {
 	if file == "" {
 		file = c.file
 	} else {
 		c.file = file
 	}
+	if file == "" {
+		return errors.New("no file to save config to")
+	}
+	l, err := lockFile(file, c.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
 	// save core file
-	return c.saveFile(file)
+	return c.saveFile(file, false)
 }
 
-// saveFile save config info in specified file.
-func (c *Config) saveFile(file string) error {
-	f, err := os.OpenFile(file, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
+// saveFile save config info in specified file, if align is true the values
+// of every section are lined up. If file is a symlink its target is replaced,
+// the link is kept.
+func (c *Config) saveFile(file string, align bool) error {
+	file, err := resolveFile(file)
+	if err != nil {
+		return err
+	}
+	var (
+		mode os.FileMode = 0644
+		dir              = filepath.Dir(file)
+	)
+	fi, err := os.Stat(file)
+	if err == nil {
+		mode = fi.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	f, err := createTemp(dir, "."+filepath.Base(file)+".tmp", mode)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	if err = c.writeFile(f, mode, fi, align); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if fi != nil && c.Backups > 0 {
+		if err = c.backup(file); err != nil {
+			return err
+		}
+	}
+	if err = os.Rename(tmp, file); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// createTemp create a new file in dir named prefix and a random number, like
+// os.CreateTemp but with perm, to which the umask applies.
+func createTemp(dir, prefix string, perm os.FileMode) (*os.File, error) {
+	for i := 0; i < 10000; i++ {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if os.IsExist(err) {
+			continue
+		}
+		return f, err
+	}
+	return nil, errors.New(fmt.Sprintf("cannot create temporary file for: %s", prefix))
+}
+
+// resolveFile return the file a symlink points to, or file itself if it is
+// not a symlink or does not exist yet.
+func resolveFile(file string) (string, error) {
+	target, err := filepath.EvalSymlinks(file)
+	if err == nil {
+		return target, nil
+	}
+	if os.IsNotExist(err) {
+		return file, nil
+	}
+	return "", err
+}
+
+// writeFile write config info to the temporary file f, then apply the mode and
+// owner of the original file and flush it to disk.
+func (c *Config) writeFile(f *os.File, mode os.FileMode, fi os.FileInfo, align bool) error {
+	w := bufio.NewWriter(f)
+	if err := c.write(w, align); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if fi != nil {
+		// a new file keeps the umask applied at creation
+		if err := f.Chmod(mode); err != nil {
+			return err
+		}
+		if err := chown(f, fi); err != nil {
+			return err
+		}
+	}
+	return f.Sync()
+}
+
+// write write config info to w, if align is true the values of every
+// section are lined up.
+func (c *Config) write(w io.Writer, align bool) error {
 	// sections
 	for _, section := range c.dataOrder {
 		data, _ := c.data[section]
 		// comments
 		for _, comment := range data.comments {
-			if _, err := f.WriteString(fmt.Sprintf("%s%c", comment, CRLF)); err != nil {
+			if _, err := fmt.Fprintf(w, "%s%c", comment, CRLF); err != nil {
 				return err
 			}
 		}
 		// section
-		if _, err := f.WriteString(fmt.Sprintf("[%s]%c", section, CRLF)); err != nil {
+		if _, err := fmt.Fprintf(w, "[%s]%c", section, CRLF); err != nil {
 			return err
 		}
 		// key-values
+		width := 0
+		if align {
+			for _, k := range data.dataOrder {
+				if len(k) > width {
+					width = len(k)
+				}
+			}
+		}
 		for _, k := range data.dataOrder {
 			v, _ := data.data[k]
 			// comments
 			for _, comment := range data.dataComments[k] {
-				if _, err := f.WriteString(fmt.Sprintf("%s%c", comment, CRLF)); err != nil {
+				if _, err := fmt.Fprintf(w, "%s%c", comment, CRLF); err != nil {
 					return err
 				}
 			}
 			// key-value
-			if _, err := f.WriteString(fmt.Sprintf("%s%s%s%c", k, c.Spliter, v, CRLF)); err != nil {
+			if _, err := fmt.Fprintf(w, "%s%c", c.keyValue(k, v, data.ops[k], width), CRLF); err != nil {
 				return err
 			}
 		}
 	}
+	// trailing comments
+	for _, comment := range c.comments {
+		if _, err := fmt.Fprintf(w, "%s%c", comment, CRLF); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// keyValue format a key-value line, padding the key to width.
+func (c *Config) keyValue(k, v string, op, width int) string {
+	pad := ""
+	if n := width - len(k); n > 0 {
+		pad = strings.Repeat(" ", n)
+	}
+	switch op {
+	case opDelete:
+		return DeleteKey + k
+	case opAppend:
+		if strings.TrimSpace(c.Spliter) == "" {
+			return k + pad + c.Spliter + AppendKey + "= " + v
+		}
+		if strings.HasPrefix(c.Spliter, "=") {
+			// "+" next to the spliter, so "key +=" is read back as an append
+			if width == 0 {
+				return k + AppendKey + c.Spliter + v
+			}
+			return k + pad + " " + AppendKey + c.Spliter + " " + v
+		}
+		// "+=" at the start of the value, such as "key: += value"
+		if width == 0 {
+			return k + c.Spliter + AppendKey + "= " + v
+		}
+		return k + pad + " " + c.Spliter + " " + AppendKey + "= " + v
+	}
+	if width == 0 {
+		return k + c.Spliter + v
+	}
+	if strings.TrimSpace(c.Spliter) == "" {
+		return k + pad + c.Spliter + v
+	}
+	return k + pad + " " + c.Spliter + " " + v
+}
+
+// backup keep a timestamped copy of file and remove the oldest copies beyond
+// c.Backups.
+func (c *Config) backup(file string) error {
+	bak := fmt.Sprintf("%s.%s%s", file, time.Now().Format(BackupTime), BackupExt)
+	if err := os.Link(file, bak); err != nil {
+		if err = copyFile(file, bak); err != nil {
+			return err
+		}
+	}
+	baks, err := Backups(file)
+	if err != nil {
+		return err
+	}
+	for len(baks) > c.Backups {
+		if err = os.Remove(baks[0]); err != nil {
+			return err
+		}
+		baks = baks[1:]
+	}
+	return nil
+}
+
+// Backups return the backup files of the specified config file, oldest first.
+func Backups(file string) ([]string, error) {
+	dir, base := filepath.Split(file)
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	baks := []string{}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, base+".") && strings.HasSuffix(name, BackupExt) {
+			ts := name[len(base)+1 : len(name)-len(BackupExt)]
+			if _, err := time.Parse(BackupTime, ts); err == nil {
+				baks = append(baks, filepath.Join(dir, name))
+			}
+		}
+	}
+	// the timestamp format sorts lexically
+	sort.Strings(baks)
+	return baks, nil
+}
+
+// copyFile copy src to dst with the mode of src.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// listDelim return the delimiter of appended values, c may be nil.
+func (c *Config) listDelim() string {
+	if c == nil || c.Delim == "" {
+		return Delim
+	}
+	return c.Delim
+}
+
+// clone return an empty Config with the same options as c.
+func (c *Config) clone() *Config {
+	return &Config{Comment: c.Comment, Spliter: c.Spliter, Backups: c.Backups, LockTimeout: c.LockTimeout, DurationUnit: c.DurationUnit, LegacyMemSize: c.LegacyMemSize, StrictBool: c.StrictBool, IntLiterals: c.IntLiterals, KeepListSpace: c.KeepListSpace, Delim: c.Delim, CollectErrors: c.CollectErrors, DisallowUnknown: c.DisallowUnknown, file: c.file, data: map[string]*Section{}}
+}
+
 // Reload reload the config file and return a new Config.
 func (c *Config) Reload() (*Config, error) {
-	nc := &Config{Comment: c.Comment, Spliter: c.Spliter, file: c.file, data: map[string]*Section{}}
+	nc := c.clone()
 	if err := nc.Parse(c.file); err != nil {
 		return nil, err
 	}
@@ -247,7 +611,9 @@ func (c *Config) Reload() (*Config, error) {
 // Add add a new key-value configuration for the section.
 func (s *Section) Add(k, v string, comments ...string) {
 	if _, ok := s.data[k]; !ok {
-		s.dataOrder = append(s.dataOrder, k)
+		if s.ops[k] != opDelete {
+			s.dataOrder = append(s.dataOrder, k)
+		}
 		for _, comment := range comments {
 			for _, line := range strings.Split(comment, string(CRLF)) {
 				s.dataComments[k] = append(s.dataComments[k], fmt.Sprintf("%s%s", s.Comment, line))
@@ -255,11 +621,14 @@ func (s *Section) Add(k, v string, comments ...string) {
 		}
 	}
 	s.data[k] = v
+	delete(s.ops, k)
 }
 
 // Remove remove the specified key configuration for the section.
 func (s *Section) Remove(k string) {
 	delete(s.data, k)
+	delete(s.ops, k)
+	delete(s.lines, k)
 	for i, key := range s.dataOrder {
 		if key == k {
 			s.dataOrder = append(s.dataOrder[:i], s.dataOrder[i+1:]...)
@@ -278,504 +647,438 @@ func (e *NoKeyError) Error() string {
 	return fmt.Sprintf("key: \"%s\" not found in [%s]", e.Key, e.Section)
 }
 
+// noKey return a NoKeyError for key, s may be nil.
+func (s *Section) noKey(key string) error {
+	if s == nil {
+		return &NoKeyError{Key: key}
+	}
+	return &NoKeyError{Key: key, Section: s.Name}
+}
+
+// A ValueError describes a config value that could not be converted.
+type ValueError struct {
+	Section string
+	Key     string
+	Value   string
+	Err     error
+}
+
+func (e *ValueError) Error() string {
+	return fmt.Sprintf("key: \"%s\" in [%s] invalid value %q: %s", e.Key, e.Section, e.Value, e.Err)
+}
+
+func (e *ValueError) Unwrap() error {
+	return e.Err
+}
+
+// valueError wrap a conversion error of key, s may be nil.
+func (s *Section) valueError(key, v string, err error) error {
+	if err == nil {
+		return nil
+	}
+	name := ""
+	if s != nil {
+		name = s.Name
+	}
+	return &ValueError{Section: name, Key: key, Value: v, Err: err}
+}
+
+// Lookup get config raw value, ok is false if the key does not exist.
+//
+// All the getters are safe to call on a nil section, such as the result of
+// Config.Get for a missing section, they behave as if no key exists.
+func (s *Section) Lookup(key string) (v string, ok bool) {
+	if s == nil {
+		return "", false
+	}
+	v, ok = s.data[key]
+	return
+}
+
+// Line return the line of key in the parsed file, 0 if the key was not read
+// from a file, s may be nil.
+func (s *Section) Line(key string) int {
+	if s == nil {
+		return 0
+	}
+	return s.lines[key]
+}
+
+// Has return true if the key exists.
+func (s *Section) Has(key string) bool {
+	_, ok := s.Lookup(key)
+	return ok
+}
+
 // String get config string value.
 func (s *Section) String(key string) (string, error) {
-	if v, ok := s.data[key]; ok {
+	if v, ok := s.Lookup(key); ok {
 		return v, nil
 	} else {
-		return "", &NoKeyError{Key: key, Section: s.Name}
+		return "", s.noKey(key)
 	}
 }
 
-// Strings get config []string value.
+// Strings get config []string value split by delim.
+//
+// An element may be double-quoted to contain delim or leading and trailing
+// spaces, such as `a, "b, c", " d "`, inside quotes \" and \\ are escapes.
+// Outside quotes a backslash escapes delim. Spaces around unquoted elements
+// are trimmed unless Config.KeepListSpace is set. An empty value is an empty
+// list.
 func (s *Section) Strings(key, delim string) ([]string, error) {
-	if v, ok := s.data[key]; ok {
-		return strings.Split(v, delim), nil
+	if v, ok := s.Lookup(key); ok {
+		return splitList(v, delim, s.config().keepListSpace())
+	} else {
+		return nil, s.noKey(key)
+	}
+}
+
+// Map get config map[string]string value, pairs are split by delim and key
+// and value by sep, such as "a=1,b=2". See Strings for quoting.
+func (s *Section) Map(key, delim, sep string) (map[string]string, error) {
+	if v, ok := s.Lookup(key); ok {
+		keep := s.config().keepListSpace()
+		strs, err := splitList(v, delim, keep)
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]string, len(strs))
+		for _, str := range strs {
+			k, e, err := splitPair(str, sep, keep)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = e
+		}
+		return m, nil
 	} else {
-		return nil, &NoKeyError{Key: key, Section: s.Name}
+		return nil, s.noKey(key)
 	}
 }
 
 // Int get config int value.
+//
+// When Config.IntLiterals is set Go integer literals are accepted, such as
+// "0x1F", "0o755", "0b101" or "1_000_000".
 func (s *Section) Int(key string) (int64, error) {
-	if v, ok := s.data[key]; ok {
-		return strconv.ParseInt(v, 10, 64)
+	if v, ok := s.Lookup(key); ok {
+		return strconv.ParseInt(v, s.config().intBase(), 64)
 	} else {
-		return 0, &NoKeyError{Key: key, Section: s.Name}
+		return 0, s.noKey(key)
 	}
 }
 
-// Uint get config uint value.
+// Uint get config uint value, see Int for Config.IntLiterals.
 func (s *Section) Uint(key string) (uint64, error) {
-	if v, ok := s.data[key]; ok {
-		return strconv.ParseUint(v, 10, 64)
+	if v, ok := s.Lookup(key); ok {
+		return strconv.ParseUint(v, s.config().intBase(), 64)
+	} else {
+		return 0, s.noKey(key)
+	}
+}
+
+// intBase return the base to parse integers in, c may be nil.
+func (c *Config) intBase() int {
+	if c != nil && c.IntLiterals {
+		return 0
+	}
+	return 10
+}
+
+// FileMode get config file permission value, always in octal such as
+// "0640", "640" or "0o2755". The setuid, setgid and sticky bits are
+// accepted.
+func (s *Section) FileMode(key string) (os.FileMode, error) {
+	if v, ok := s.Lookup(key); ok {
+		return parseFileMode(v)
 	} else {
-		return 0, &NoKeyError{Key: key, Section: s.Name}
+		return 0, s.noKey(key)
 	}
 }
 
+func parseFileMode(v string) (os.FileMode, error) {
+	o := strings.TrimPrefix(strings.TrimPrefix(v, "0o"), "0O")
+	n, err := strconv.ParseUint(o, 8, 32)
+	if err != nil || n > 07777 {
+		return 0, errors.New(fmt.Sprintf("invalid file mode: %q", v))
+	}
+	mode := os.FileMode(n & 0777)
+	if n&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if n&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if n&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode, nil
+}
+
 // Float get config float value.
 func (s *Section) Float(key string) (float64, error) {
-	if v, ok := s.data[key]; ok {
+	if v, ok := s.Lookup(key); ok {
 		return strconv.ParseFloat(v, 64)
 	} else {
-		return 0, &NoKeyError{Key: key, Section: s.Name}
+		return 0, s.noKey(key)
 	}
 }
 
 // Bool get config boolean value.
 //
-// "yes", "1", "y", "true", "enable" means true.
+// "yes", "1", "y", "true", "enable", "enabled", "on" means true.
 //
-// "no", "0", "n", "false", "disable" means false.
+// "no", "0", "n", "false", "disable", "disabled", "off" means false.
 //
-// if the specified value unknown then return false.
+// More words can be added by RegisterBool, case is ignored.
+//
+// if the specified value unknown then return false, or an InvalidBoolError
+// when Config.StrictBool is set.
 func (s *Section) Bool(key string) (bool, error) {
-	if v, ok := s.data[key]; ok {
-		v = strings.ToLower(v)
-		return parseBool(v), nil
+	if v, ok := s.Lookup(key); ok {
+		return parseBool(v, s.config().strictBool())
 	} else {
-		return false, &NoKeyError{Key: key, Section: s.Name}
+		return false, s.noKey(key)
 	}
 }
 
-func parseBool(v string) bool {
-	if v == "true" || v == "yes" || v == "1" || v == "y" || v == "enable" {
-		return true
-	} else if v == "false" || v == "no" || v == "0" || v == "n" || v == "disable" {
-		return false
+// BoolStrict get config boolean value like Bool, an unknown value is always
+// an InvalidBoolError.
+func (s *Section) BoolStrict(key string) (bool, error) {
+	if v, ok := s.Lookup(key); ok {
+		return parseBool(v, true)
 	} else {
-		return false
+		return false, s.noKey(key)
+	}
+}
+
+// An InvalidBoolError describes a value that is not a known boolean word.
+type InvalidBoolError struct {
+	Value string
+}
+
+func (e *InvalidBoolError) Error() string {
+	return fmt.Sprintf("invalid boolean: %q", e.Value)
+}
+
+var (
+	boolsMu sync.RWMutex
+	bools   = map[string]bool{
+		"true": true, "yes": true, "1": true, "y": true, "enable": true, "enabled": true, "on": true,
+		"false": false, "no": false, "0": false, "n": false, "disable": false, "disabled": false, "off": false,
+	}
+)
+
+// RegisterBool add a word to the boolean vocabulary, such as
+// RegisterBool("active", true). Case is ignored.
+func RegisterBool(word string, v bool) {
+	boolsMu.Lock()
+	bools[strings.ToLower(word)] = v
+	boolsMu.Unlock()
+}
+
+// strictBool return true if unknown boolean words are errors, c may be nil.
+func (c *Config) strictBool() bool {
+	return c != nil && c.StrictBool
+}
+
+func parseBool(v string, strict bool) (bool, error) {
+	boolsMu.RLock()
+	b, ok := bools[strings.ToLower(strings.TrimSpace(v))]
+	boolsMu.RUnlock()
+	if !ok && strict {
+		return false, &InvalidBoolError{Value: v}
 	}
+	return b, nil
 }
 
-// Byte get config byte number value.
+// MemSize get config byte number value.
 //
-// 1kb = 1k = 1024.
+// Units are case-insensitive and may follow a space, the number may have a
+// fraction, such as "1.5GB" or "512 MiB":
 //
-// 1mb = 1m = 1024 * 1024.
+// 1b = 1, 1k = 1kb = 1000, 1m = 1mb = 1000 * 1000, up to 1pb.
 //
-// 1gb = 1g = 1024 * 1024 * 1024.
-func (s *Section) MemSize(key string) (int, error) {
-	if v, ok := s.data[key]; ok {
-		return parseMemory(v)
+// 1ki = 1kib = 1024, 1mi = 1mib = 1024 * 1024, up to 1pib.
+//
+// When Config.LegacyMemSize is set the SI units are powers of 1024 too.
+//
+// Compare sizes with the KiB, MiB, GiB, TiB and PiB constants, which match
+// the binary units. The deprecated KB, MB and GB constants are binary as
+// well and do not equal the "kb", "mb" and "gb" units.
+func (s *Section) MemSize(key string) (int64, error) {
+	if v, ok := s.Lookup(key); ok {
+		b, err := parseMemory(v, s.config().legacyMemSize())
+		if err != nil {
+			return 0, err
+		}
+		if b > math.MaxInt64 {
+			return 0, errors.New(fmt.Sprintf("memory size: %s overflows int64", v))
+		}
+		return int64(b), nil
 	} else {
-		return 0, &NoKeyError{Key: key, Section: s.Name}
-	}
-}
-
-func parseMemory(v string) (int, error) {
-	unit := Byte
-	subIdx := len(v)
-	if strings.HasSuffix(v, "k") {
-		unit = KB
-		subIdx = subIdx - 1
-	} else if strings.HasSuffix(v, "kb") {
-		unit = KB
-		subIdx = subIdx - 2
-	} else if strings.HasSuffix(v, "m") {
-		unit = MB
-		subIdx = subIdx - 1
-	} else if strings.HasSuffix(v, "mb") {
-		unit = MB
-		subIdx = subIdx - 2
-	} else if strings.HasSuffix(v, "g") {
-		unit = GB
-		subIdx = subIdx - 1
-	} else if strings.HasSuffix(v, "gb") {
-		unit = GB
-		subIdx = subIdx - 2
-	}
-	b, err := strconv.ParseInt(v[:subIdx], 10, 64)
-	if err != nil {
-		return 0, err
+		return 0, s.noKey(key)
+	}
+}
+
+// MemSizeUint get config byte number value as uint64, see MemSize.
+func (s *Section) MemSizeUint(key string) (uint64, error) {
+	if v, ok := s.Lookup(key); ok {
+		return parseMemory(v, s.config().legacyMemSize())
+	} else {
+		return 0, s.noKey(key)
 	}
-	return int(b) * unit, nil
 }
 
-// Duration get config second value.
+// Duration get config time.Duration value.
 //
-// 1s = 1sec = 1.
+// 1s = 1sec = 1second, 1m = 1min, 1h = 1hour, 1d = 1day = 24h, 1w = 7d.
 //
-// 1m = 1min = 60.
+// ns, us, ms and the time.ParseDuration syntax are accepted as well, values
+// can be compound and fractional, such as "1h30m" or "1.5s". A fraction finer
+// than 1ns, such as "1.5" in nanoseconds, is an error.
 //
-// 1h = 1hour = 60 * 60.
+// A bare number is in Config.DurationUnit, nanoseconds by default.
 func (s *Section) Duration(key string) (time.Duration, error) {
-	if v, ok := s.data[key]; ok {
-		if t, err := parseTime(v); err != nil {
-			return 0, err
-		} else {
-			return time.Duration(t), nil
-		}
+	if v, ok := s.Lookup(key); ok {
+		return parseTime(v, s.config().durationUnit())
 	} else {
-		return 0, &NoKeyError{Key: key, Section: s.Name}
-	}
-}
-
-func parseTime(v string) (int64, error) {
-	unit := int64(time.Nanosecond)
-	subIdx := len(v)
-	if strings.HasSuffix(v, "ms") {
-		unit = int64(time.Millisecond)
-		subIdx = subIdx - 2
-	} else if strings.HasSuffix(v, "s") {
-		unit = int64(time.Second)
-		subIdx = subIdx - 1
-	} else if strings.HasSuffix(v, "sec") {
-		unit = int64(time.Second)
-		subIdx = subIdx - 3
-	} else if strings.HasSuffix(v, "m") {
-		unit = int64(time.Minute)
-		subIdx = subIdx - 1
-	} else if strings.HasSuffix(v, "min") {
-		unit = int64(time.Minute)
-		subIdx = subIdx - 3
-	} else if strings.HasSuffix(v, "h") {
-		unit = int64(time.Hour)
-		subIdx = subIdx - 1
-	} else if strings.HasSuffix(v, "hour") {
-		unit = int64(time.Hour)
-		subIdx = subIdx - 4
-	}
-	b, err := strconv.ParseInt(v[:subIdx], 10, 64)
-	if err != nil {
-		return 0, err
+		return 0, s.noKey(key)
+	}
+}
+
+// StringOr get config string value, def is returned if the key does not exist.
+func (s *Section) StringOr(key, def string) (string, error) {
+	if !s.Has(key) {
+		return def, nil
+	}
+	return s.String(key)
+}
+
+// IntOr get config int value, def is returned if the key does not exist.
+// Unlike a default applied on any error, an invalid value is still reported.
+func (s *Section) IntOr(key string, def int64) (int64, error) {
+	if !s.Has(key) {
+		return def, nil
+	}
+	return s.Int(key)
+}
+
+// UintOr get config uint value, def is returned if the key does not exist.
+func (s *Section) UintOr(key string, def uint64) (uint64, error) {
+	if !s.Has(key) {
+		return def, nil
+	}
+	return s.Uint(key)
+}
+
+// FloatOr get config float value, def is returned if the key does not exist.
+func (s *Section) FloatOr(key string, def float64) (float64, error) {
+	if !s.Has(key) {
+		return def, nil
+	}
+	return s.Float(key)
+}
+
+// BoolOr get config boolean value, def is returned if the key does not exist.
+func (s *Section) BoolOr(key string, def bool) (bool, error) {
+	if !s.Has(key) {
+		return def, nil
 	}
-	return b * unit, nil
+	return s.Bool(key)
+}
+
+// DurationOr get config duration value, def is returned if the key does not
+// exist.
+func (s *Section) DurationOr(key string, def time.Duration) (time.Duration, error) {
+	if !s.Has(key) {
+		return def, nil
+	}
+	return s.Duration(key)
+}
+
+// MemSizeOr get config byte number value, def is returned if the key does
+// not exist.
+func (s *Section) MemSizeOr(key string, def int64) (int64, error) {
+	if !s.Has(key) {
+		return def, nil
+	}
+	return s.MemSize(key)
 }
 
 // Keys return all the section keys.
 func (s *Section) Keys() []string {
 	keys := []string{}
+	if s == nil {
+		return keys
+	}
 	for k, _ := range s.data {
 		keys = append(keys, k)
 	}
 	return keys
 }
 
-// An InvalidUnmarshalError describes an invalid argument passed to Unmarshal.
-// (The argument to Unmarshal must be a non-nil pointer.)
-type InvalidUnmarshalError struct {
-	Type reflect.Type
+// SetInt add or update a int value.
+func (s *Section) SetInt(key string, v int64, comments ...string) {
+	s.Add(key, strconv.FormatInt(v, 10), comments...)
 }
 
-func (e *InvalidUnmarshalError) Error() string {
-	if e.Type == nil {
-		return "goconf: Unmarshal(nil)"
-	}
-	if e.Type.Kind() != reflect.Ptr {
-		return "goconf: Unmarshal(non-pointer " + e.Type.String() + ")"
-	}
-	return "goconf: Unmarshal(nil " + e.Type.String() + ")"
+// SetUint add or update a uint value.
+func (s *Section) SetUint(key string, v uint64, comments ...string) {
+	s.Add(key, strconv.FormatUint(v, 10), comments...)
 }
 
-// Unmarshal parses the goconf struct and stores the result in the value
-// pointed to by v.
-//
-// Struct values encode as goconf objects. Each exported struct field
-// becomes a member of the object unless
-//   - the field's tag is "-", or
-//   - the field is empty and its tag specifies the "omitempty" option.
-// The empty values are false, 0, any
-// nil pointer or interface value, and any array, slice, map, or string of
-// length zero. The object's section and key string is the struct field name
-// but can be specified in the struct field's tag value. The "goconf" key in
-// the struct field's tag value is the key name, followed by an optional comma
-// and options. Examples:
-//
-//   // Field is ignored by this package.
-//   Field int `goconf:"-"`
-//
-//   // Field appears in goconf section "base" as key "myName".
-//   Field int `goconf:"base:myName"`
-//
-//   // Field appears in goconf section "base" as key "myName", the value split
-//   // by delimiter ",".
-//   Field []string `goconf:"base:myName:,"`
-//
-//   // Field appears in goconf section "base" as key "myName", the value split
-//   // by delimiter "," and key-value is splited by "=".
-//   Field map[int]string `goconf:"base:myName:,"`
-//
-//   // Field appears in goconf section "base" as key "myName", the value
-//   // conver to time.Duration. When has extra tag "time", then goconf can
-//   // parse such "1h", "1s" config values.
-//   //
-//   // Note the extra tag "time" only effect the int64 (time.Duration is int64)
-//   Field time.Duration `goconf:"base:myName:time"`
-//
-//   // Field appears in goconf section "base" as key "myName", when has extra
-//   // tag, then goconf can parse like "1gb", "1mb" config values.
-//   //
-//   // Note the extra tag "memory" only effect the int (memory size is int).
-//   Field int `goconf:"base:myName:memory"`
-//
-func (c *Config) Unmarshal(v interface{}) error {
-	vv := reflect.ValueOf(v)
-	if vv.Kind() != reflect.Ptr || vv.IsNil() {
-		return &InvalidUnmarshalError{reflect.TypeOf(v)}
-	}
-	rv := vv.Elem()
-	rt := rv.Type()
-	n := rv.NumField()
-	// enum every struct field
-	for i := 0; i < n; i++ {
-		vf := rv.Field(i)
-		tf := rt.Field(i)
-		tag := tf.Tag.Get("goconf")
-		// if tag empty or "-" ignore
-		if tag == "-" || tag == "" || tag == "omitempty" {
-			continue
-		}
-		tagArr := strings.SplitN(tag, ":", 3)
-		if len(tagArr) < 2 {
-			return errors.New(fmt.Sprintf("error tag: %s, must be section:field:delim(optional)", tag))
-		}
-		section := tagArr[0]
-		key := tagArr[1]
-		s := c.Get(section)
-		if s == nil {
-			// no config section
-			continue
-		}
-		value, ok := s.data[key]
-		if !ok {
-			// no confit key
-			continue
-		}
-		switch vf.Kind() {
-		case reflect.String:
-			vf.SetString(value)
-		case reflect.Bool:
-			vf.SetBool(parseBool(value))
-		case reflect.Float32:
-			if tmp, err := strconv.ParseFloat(value, 32); err != nil {
-				return err
-			} else {
-				vf.SetFloat(tmp)
-			}
-		case reflect.Float64:
-			if tmp, err := strconv.ParseFloat(value, 64); err != nil {
-				return err
-			} else {
-				vf.SetFloat(tmp)
-			}
-		case reflect.Int:
-			if len(tagArr) == 3 {
-				format := tagArr[2]
-				// parse memory size
-				if format == "memory" {
-					if tmp, err := parseMemory(value); err != nil {
-						return err
-					} else {
-						vf.SetInt(int64(tmp))
-					}
-				} else {
-					return errors.New(fmt.Sprintf("unknown tag: %s in struct field: %s (support tags: \"memory\")", format, tf.Name))
-				}
-			} else {
-				if tmp, err := strconv.ParseInt(value, 10, 32); err != nil {
-					return err
-				} else {
-					vf.SetInt(tmp)
-				}
-			}
-		case reflect.Int8:
-			if tmp, err := strconv.ParseInt(value, 10, 8); err != nil {
-				return err
-			} else {
-				vf.SetInt(tmp)
-			}
-		case reflect.Int16:
-			if tmp, err := strconv.ParseInt(value, 10, 16); err != nil {
-				return err
-			} else {
-				vf.SetInt(tmp)
-			}
-		case reflect.Int32:
-			if tmp, err := strconv.ParseInt(value, 10, 32); err != nil {
-				return err
-			} else {
-				vf.SetInt(tmp)
-			}
-		case reflect.Int64:
-			if len(tagArr) == 3 {
-				format := tagArr[2]
-				// parse time
-				if format == "time" {
-					if tmp, err := parseTime(value); err != nil {
-						return err
-					} else {
-						vf.SetInt(tmp)
-					}
-				} else {
-					return errors.New(fmt.Sprintf("unknown tag: %s in struct field: %s (support tags: \"time\")", format, tf.Name))
-				}
-			} else {
-				if tmp, err := strconv.ParseInt(value, 10, 64); err != nil {
-					return err
-				} else {
-					vf.SetInt(tmp)
-				}
-			}
-		case reflect.Uint:
-			if tmp, err := strconv.ParseUint(value, 10, 32); err != nil {
-				return err
-			} else {
-				vf.SetUint(tmp)
-			}
-		case reflect.Uint8:
-			if tmp, err := strconv.ParseUint(value, 10, 8); err != nil {
-				return err
-			} else {
-				vf.SetUint(tmp)
-			}
-		case reflect.Uint16:
-			if tmp, err := strconv.ParseUint(value, 10, 16); err != nil {
-				return err
-			} else {
-				vf.SetUint(tmp)
-			}
-		case reflect.Uint32:
-			if tmp, err := strconv.ParseUint(value, 10, 32); err != nil {
-				return err
-			} else {
-				vf.SetUint(tmp)
-			}
-		case reflect.Uint64:
-			if tmp, err := strconv.ParseUint(value, 10, 64); err != nil {
-				return err
-			} else {
-				vf.SetUint(tmp)
-			}
-		case reflect.Slice:
-			delim := ","
-			if len(tagArr) > 2 {
-				delim = tagArr[2]
-			}
-			strs := strings.Split(value, delim)
-			sli := reflect.MakeSlice(tf.Type, 0, len(strs))
-			for _, str := range strs {
-				vv, err := getValue(tf.Type.Elem().String(), str)
-				if err != nil {
-					return err
-				}
-				sli = reflect.Append(sli, vv)
-			}
-			vf.Set(sli)
-		case reflect.Map:
-			delim := ","
-			if len(tagArr) > 2 {
-				delim = tagArr[2]
-			}
-			strs := strings.Split(value, delim)
-			m := reflect.MakeMap(tf.Type)
-			for _, str := range strs {
-				mapStrs := strings.SplitN(str, "=", 2)
-				if len(mapStrs) < 2 {
-					return errors.New(fmt.Sprintf("error map: %s, must be split by \"=\"", str))
-				}
-				vk, err := getValue(tf.Type.Key().String(), mapStrs[0])
-				if err != nil {
-					return err
-				}
-				vv, err := getValue(tf.Type.Elem().String(), mapStrs[1])
-				if err != nil {
-					return err
-				}
-				m.SetMapIndex(vk, vv)
-			}
-			vf.Set(m)
-		default:
-			return errors.New(fmt.Sprintf("cannot unmarshall unsuported kind: %s into struct field: %s", vf.Kind().String(), tf.Name))
-		}
+// SetFloat add or update a float value, formatted with the fewest digits
+// that read back as the same float64.
+func (s *Section) SetFloat(key string, v float64, comments ...string) {
+	s.Add(key, strconv.FormatFloat(v, 'g', -1, 64), comments...)
+}
+
+// SetBool add or update a boolean value as "true" or "false".
+func (s *Section) SetBool(key string, v bool, comments ...string) {
+	s.Add(key, strconv.FormatBool(v), comments...)
+}
+
+// SetDuration add or update a duration value in the form read by Duration,
+// such as "90s" or "2h".
+func (s *Section) SetDuration(key string, v time.Duration, comments ...string) {
+	s.Add(key, formatTime(v), comments...)
+}
+
+// SetMemSize add or update a byte number value in the form read by MemSize,
+// such as "512MiB" or "1GiB".
+func (s *Section) SetMemSize(key string, v uint64, comments ...string) {
+	s.Add(key, formatMemory(v), comments...)
+}
+
+// SetStrings add or update a []string value joined by delim, elements are
+// quoted where needed so Strings returns them unchanged. An error is returned
+// if an element contains a newline.
+func (s *Section) SetStrings(key string, v []string, delim string, comments ...string) error {
+	value, err := joinList(v, delim)
+	if err != nil {
+		return errors.New(fmt.Sprintf("key: %s %s", key, err))
 	}
+	s.Add(key, value, comments...)
 	return nil
 }
 
-// getValue parse String to the type "t" reflect.Value.
-func getValue(t, v string) (reflect.Value, error) {
-	var vv reflect.Value
-	switch t {
-	case "bool":
-		d := parseBool(v)
-		vv = reflect.ValueOf(d)
-	case "int":
-		d, err := strconv.ParseInt(v, 10, 32)
-		if err != nil {
-			return vv, err
-		}
-		vv = reflect.ValueOf(int(d))
-	case "int8":
-		d, err := strconv.ParseInt(v, 10, 8)
-		if err != nil {
-			return vv, err
-		}
-		vv = reflect.ValueOf(int8(d))
-	case "int16":
-		d, err := strconv.ParseInt(v, 10, 16)
-		if err != nil {
-			return vv, err
+// SetMap add or update a map value as "k=v" pairs joined by delim, sorted by
+// key, in the form read by Map and Unmarshal. An error is returned if the
+// value could not be read back.
+func (s *Section) SetMap(key string, v map[string]string, delim string, comments ...string) error {
+	keys := make([]string, 0, len(v))
+	for k, e := range v {
+		if strings.Contains(k, "=") || k != strings.TrimSpace(k) || e != strings.TrimSpace(e) {
+			return errors.New(fmt.Sprintf("key: %s map pair: %q=%q contains \"=\" in key or leading or trailing space", key, k, e))
 		}
-		vv = reflect.ValueOf(int16(d))
-	case "int32":
-		d, err := strconv.ParseInt(v, 10, 32)
-		if err != nil {
-			return vv, err
-		}
-		vv = reflect.ValueOf(int32(d))
-	case "int64":
-		d, err := strconv.ParseInt(v, 10, 64)
-		if err != nil {
-			return vv, err
-		}
-		vv = reflect.ValueOf(int64(d))
-	case "uint":
-		d, err := strconv.ParseUint(v, 10, 32)
-		if err != nil {
-			return vv, err
-		}
-		vv = reflect.ValueOf(uint(d))
-	case "uint8":
-		d, err := strconv.ParseUint(v, 10, 8)
-		if err != nil {
-			return vv, err
-		}
-		vv = reflect.ValueOf(uint8(d))
-	case "uint16":
-		d, err := strconv.ParseUint(v, 10, 16)
-		if err != nil {
-			return vv, err
-		}
-		vv = reflect.ValueOf(uint16(d))
-	case "uint32":
-		d, err := strconv.ParseUint(v, 10, 32)
-		if err != nil {
-			return vv, err
-		}
-		vv = reflect.ValueOf(uint32(d))
-	case "uint64":
-		d, err := strconv.ParseUint(v, 10, 64)
-		if err != nil {
-			return vv, err
-		}
-		vv = reflect.ValueOf(uint64(d))
-	case "float32":
-		d, err := strconv.ParseFloat(v, 32)
-		if err != nil {
-			return vv, err
-		}
-		vv = reflect.ValueOf(float32(d))
-	case "float64":
-		d, err := strconv.ParseFloat(v, 64)
-		if err != nil {
-			return vv, err
-		}
-		vv = reflect.ValueOf(float64(d))
-	case "string":
-		vv = reflect.ValueOf(v)
-	default:
-		return vv, errors.New(fmt.Sprintf("unkown type: %s", t))
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+v[k])
 	}
-	return vv, nil
+	return s.SetStrings(key, pairs, delim, comments...)
 }
@@ -1,6 +1,11 @@
 package goconf
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -92,8 +97,8 @@ func TestSection(t *testing.T) {
 		t.Errorf("core.MemSize(\"%s\") failed (%s)", key, err.Error())
 		t.FailNow()
 	} else {
-		if buf != 1*1024*1024*1024 {
-			t.Errorf("%s not equals 1*1024*1024*1024", key)
+		if buf != 1*1000*1000*1000 {
+			t.Errorf("%s not equals 1*1000*1000*1000", key)
 			t.FailNow()
 		}
 	}
@@ -138,21 +143,22 @@ func TestSection(t *testing.T) {
 		}
 	}
 	test1.Add("id4", "goconf baby", " hahah\n heihei,woshishei")
-	save := "./examples/conf_reload.txt"
+	dir := t.TempDir()
+	save := filepath.Join(dir, "conf_reload.txt")
 	if err := conf.Save(save); err != nil {
 		t.Errorf("conf.Save(\"%s\") failed (%s)", save, err.Error())
 		t.FailNow()
 	}
 
 	test1.Remove("id4")
-	save = "./examples/conf_reload1.txt"
+	save = filepath.Join(dir, "conf_reload1.txt")
 	if err := conf.Save(save); err != nil {
 		t.Errorf("conf.Save(\"%s\") failed (%s)", save, err.Error())
 		t.FailNow()
 	}
 
 	conf.Remove("test1")
-	save = "./examples/conf_reload2.txt"
+	save = filepath.Join(dir, "conf_reload2.txt")
 	if err := conf.Save(save); err != nil {
 		t.Errorf("conf.Save(\"%s\") failed (%s)", save, err.Error())
 		t.FailNow()
@@ -216,8 +222,8 @@ func TestSection(t *testing.T) {
 		t.Errorf("TestConfig t_1 not equals 2 * time.Hour")
 		t.FailNow()
 	}
-	if tf.Buf != 1*GB {
-		t.Errorf("TestConfig t_1 not equals 1 * GB")
+	if tf.Buf != 1e9 {
+		t.Errorf("TestConfig buf not equals 1e9")
 		t.FailNow()
 	}
 	if len(tf.M) != 2 {
@@ -243,3 +249,357 @@ func TestSection(t *testing.T) {
 		}
 	}
 }
+
+func TestSave(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "save.conf")
+	if err := os.WriteFile(file, []byte("[core]\nid 1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	c.Backups = 2
+	if err := c.Parse(file); err != nil {
+		t.Fatalf("c.Parse(\"%s\") failed (%s)", file, err.Error())
+	}
+	for i := 2; i < 6; i++ {
+		c.Get("core").Add("id", strconv.Itoa(i))
+		if err := c.Save(""); err != nil {
+			t.Fatalf("c.Save() failed (%s)", err.Error())
+		}
+	}
+	fi, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("file mode %v not equals 0600", fi.Mode().Perm())
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "[core]\nid 5\n" {
+		t.Errorf("saved file %q not equals \"[core]\\nid 5\\n\"", b)
+	}
+	baks, err := Backups(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(baks) != 2 {
+		t.Fatalf("backups %v length not equals 2", baks)
+	}
+	if b, _ = os.ReadFile(baks[1]); string(b) != "[core]\nid 4\n" {
+		t.Errorf("last backup %q not equals \"[core]\\nid 4\\n\"", b)
+	}
+	tmps, _ := filepath.Glob(filepath.Join(filepath.Dir(file), ".*.tmp*"))
+	if len(tmps) != 0 {
+		t.Errorf("temporary files %v left", tmps)
+	}
+	// a symlink is kept and its target replaced
+	link := filepath.Join(t.TempDir(), "link.conf")
+	if err = os.Symlink(file, link); err != nil {
+		t.Skipf("os.Symlink() failed (%s)", err.Error())
+	}
+	c.Backups = 0
+	c.Get("core").Add("id", "6")
+	if err = c.Save(link); err != nil {
+		t.Fatalf("c.Save(\"%s\") failed (%s)", link, err.Error())
+	}
+	if fi, err = os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("symlink replaced: %v, %v", fi, err)
+	}
+	if b, _ = os.ReadFile(file); string(b) != "[core]\nid 6\n" {
+		t.Errorf("symlink target %q not equals \"[core]\\nid 6\\n\"", b)
+	}
+}
+
+func TestSaveNewFile(t *testing.T) {
+	dir := t.TempDir()
+	c := New()
+	if err := c.Save(""); err == nil {
+		t.Errorf("c.Save(\"\") without a file not failed")
+	}
+	// a new file gets 0644 less the umask, like one created by os.OpenFile
+	f, err := os.OpenFile(filepath.Join(dir, "umask"), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := f.Stat()
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "new.conf")
+	if err = c.Save(file); err != nil {
+		t.Fatalf("c.Save(\"%s\") failed (%s)", file, err.Error())
+	}
+	fi, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != want.Mode().Perm() {
+		t.Errorf("file mode %v not equals %v", fi.Mode().Perm(), want.Mode().Perm())
+	}
+}
+
+func TestSectionOr(t *testing.T) {
+	core := conf.Get("core")
+	if id, err := core.IntOr("id", 10); err != nil || id != 1 {
+		t.Errorf("core.IntOr(\"id\") = %d, %v not equals 1", id, err)
+	}
+	if id, err := core.IntOr("none", 10); err != nil || id != 10 {
+		t.Errorf("core.IntOr(\"none\") = %d, %v not equals 10", id, err)
+	}
+	if _, err := core.IntOr("col", 10); err == nil {
+		t.Errorf("core.IntOr(\"col\") invalid value not reported")
+	}
+	if d, err := core.DurationOr("none", time.Second); err != nil || d != time.Second {
+		t.Errorf("core.DurationOr(\"none\") = %v, %v not equals 1s", d, err)
+	}
+	if !core.Has("col") || core.Has("none") {
+		t.Errorf("core.Has() wrong")
+	}
+	if v, ok := core.Lookup("col"); !ok || v != "goconf" {
+		t.Errorf("core.Lookup(\"col\") = %q, %v", v, ok)
+	}
+	// missing section
+	none := conf.Get("none")
+	if v, err := none.StringOr("col", "def"); err != nil || v != "def" {
+		t.Errorf("none.StringOr(\"col\") = %q, %v not equals \"def\"", v, err)
+	}
+	if _, err := none.String("col"); err == nil {
+		t.Errorf("none.String(\"col\") no error")
+	}
+	if none.Has("col") || len(none.Keys()) != 0 {
+		t.Errorf("nil section has keys")
+	}
+}
+
+func TestSectionSet(t *testing.T) {
+	s := New().Add("set")
+	s.SetInt("int", -3)
+	s.SetUint("uint", 3)
+	s.SetFloat("float", 0.1)
+	s.SetBool("bool", true)
+	s.SetDuration("d1", 90*time.Minute)
+	s.SetDuration("d2", 1500*time.Millisecond)
+	s.SetDuration("d3", 1500*time.Nanosecond)
+	s.SetMemSize("m1", 512*MiB)
+	s.SetMemSize("m2", 1000)
+	if err := s.SetStrings("strs", []string{"a", "b c"}, ","); err != nil {
+		t.Fatalf("s.SetStrings() failed (%s)", err.Error())
+	}
+	if err := s.SetStrings("bad", []string{"a\nb"}, ","); err == nil {
+		t.Errorf("s.SetStrings() element with newline not reported")
+	}
+	if err := s.SetMap("map", map[string]string{"2": "str1", "1": "str"}, ","); err != nil {
+		t.Fatalf("s.SetMap() failed (%s)", err.Error())
+	}
+	if v, _ := s.String("map"); v != "1=str,2=str1" {
+		t.Errorf("map %q not equals \"1=str,2=str1\"", v)
+	}
+	if v, _ := s.String("d1"); v != "1h30m" {
+		t.Errorf("d1 %q not equals \"1h30m\"", v)
+	}
+	if i, err := s.Int("int"); err != nil || i != -3 {
+		t.Errorf("int %d, %v not equals -3", i, err)
+	}
+	if u, err := s.Uint("uint"); err != nil || u != 3 {
+		t.Errorf("uint %d, %v not equals 3", u, err)
+	}
+	if f, err := s.Float("float"); err != nil || f != 0.1 {
+		t.Errorf("float %v, %v not equals 0.1", f, err)
+	}
+	if b, err := s.Bool("bool"); err != nil || !b {
+		t.Errorf("bool %v, %v not equals true", b, err)
+	}
+	for k, want := range map[string]time.Duration{"d1": 90 * time.Minute, "d2": 1500 * time.Millisecond, "d3": 1500} {
+		if d, err := s.Duration(k); err != nil || d != want {
+			t.Errorf("%s %v, %v not equals %v", k, d, err, want)
+		}
+	}
+	for k, want := range map[string]int64{"m1": 512 * MiB, "m2": 1000} {
+		if m, err := s.MemSize(k); err != nil || m != want {
+			t.Errorf("%s %v, %v not equals %v", k, m, err, want)
+		}
+	}
+	if strs, err := s.Strings("strs", ","); err != nil || len(strs) != 2 || strs[1] != "b c" {
+		t.Errorf("strs %q, %v not equals [a b c]", strs, err)
+	}
+}
+
+func TestMemSize(t *testing.T) {
+	c := parseString(t, "[core]\nbuf 1gb\n")
+	core := c.Get("core")
+	for _, m := range []struct {
+		v      string
+		legacy bool
+		b      uint64
+	}{
+		{"1gb", false, 1e9},
+		{"1GB", true, GiB},
+		{"1.5GB", false, 1.5e9},
+		{"512 MiB", false, 512 * MiB},
+		{"2TiB", false, 2 * TiB},
+		{"1pb", false, 1e15},
+		{"1k", true, KiB},
+		{"100", false, 100},
+		{"100B", false, 100},
+		{".5KiB", false, 512},
+	} {
+		if b, err := parseMemory(m.v, m.legacy); err != nil || b != m.b {
+			t.Errorf("parseMemory(%q, %v) = %d, %v not equals %d", m.v, m.legacy, b, err, m.b)
+		}
+	}
+	for _, v := range []string{"", "gb", "1xb", "-1", "16384PiB", "1.x"} {
+		if b, err := parseMemory(v, false); err == nil {
+			t.Errorf("parseMemory(%q) = %d, no error", v, b)
+		}
+	}
+	c.LegacyMemSize = true
+	if b, err := core.MemSize("buf"); err != nil || b != GiB {
+		t.Errorf("legacy MemSize(\"buf\") = %d, %v not equals GiB", b, err)
+	}
+	var tc struct {
+		Buf   uint16 `goconf:"core:buf:memory"`
+		Buf64 uint64 `goconf:"core:buf:memory"`
+	}
+	if err := c.Unmarshal(&tc); err == nil {
+		t.Errorf("Unmarshal() uint16 overflow not reported")
+	}
+	var tc1 struct {
+		Buf64 uint64 `goconf:"core:buf:memory"`
+	}
+	if err := c.Unmarshal(&tc1); err != nil || tc1.Buf64 != GiB {
+		t.Errorf("Unmarshal() Buf64 = %d, %v not equals GiB", tc1.Buf64, err)
+	}
+}
+
+func TestBool(t *testing.T) {
+	RegisterBool("Active", true)
+	c := parseString(t, "[core]\na On\nb OFF\nc active\nd enabel\n")
+	core := c.Get("core")
+	for k, want := range map[string]bool{"a": true, "b": false, "c": true, "d": false} {
+		if b, err := core.Bool(k); err != nil || b != want {
+			t.Errorf("core.Bool(%q) = %v, %v not equals %v", k, b, err, want)
+		}
+	}
+	var berr *InvalidBoolError
+	if _, err := core.BoolStrict("d"); !errors.As(err, &berr) || berr.Value != "enabel" {
+		t.Errorf("core.BoolStrict(\"d\") error %v not an InvalidBoolError", err)
+	}
+	var tc struct {
+		D bool `goconf:"core:d:strict"`
+	}
+	if err := c.Unmarshal(&tc); !errors.As(err, &berr) {
+		t.Errorf("c.Unmarshal() error %v not an InvalidBoolError", err)
+	}
+	c.StrictBool = true
+	if _, err := core.Bool("d"); !errors.As(err, &berr) {
+		t.Errorf("strict core.Bool(\"d\") error %v not an InvalidBoolError", err)
+	}
+	if _, err := Get[bool](core, "d"); !errors.As(err, &berr) {
+		t.Errorf("strict Get[bool](\"d\") error %v not an InvalidBoolError", err)
+	}
+}
+
+func TestIntLiterals(t *testing.T) {
+	c := parseString(t, "[core]\nhex 0x1F\noct 0o755\nbig 1_000_000\nmode 0640\nsticky 1777\nbad 0999\n")
+	core := c.Get("core")
+	if _, err := core.Int("hex"); err == nil {
+		t.Errorf("core.Int(\"hex\") without IntLiterals no error")
+	}
+	c.IntLiterals = true
+	for k, want := range map[string]int64{"hex": 31, "oct": 0755, "big": 1000000} {
+		if i, err := core.Int(k); err != nil || i != want {
+			t.Errorf("core.Int(%q) = %d, %v not equals %d", k, i, err, want)
+		}
+	}
+	if u, err := Get[uint8](core, "hex"); err != nil || u != 31 {
+		t.Errorf("Get[uint8](\"hex\") = %d, %v not equals 31", u, err)
+	}
+	if m, err := core.FileMode("mode"); err != nil || m != 0640 {
+		t.Errorf("core.FileMode(\"mode\") = %v, %v not equals 0640", m, err)
+	}
+	if m, err := core.FileMode("sticky"); err != nil || m != os.ModeSticky|0777 {
+		t.Errorf("core.FileMode(\"sticky\") = %v, %v", m, err)
+	}
+	if _, err := core.FileMode("bad"); err == nil {
+		t.Errorf("core.FileMode(\"bad\") no error")
+	}
+	var tc struct {
+		Mode os.FileMode `goconf:"core:oct"`
+		Big  int         `goconf:"core:big"`
+	}
+	if err := c.Unmarshal(&tc); err != nil || tc.Mode != 0755 || tc.Big != 1000000 {
+		t.Errorf("c.Unmarshal() = %+v, %v", tc, err)
+	}
+}
+
+func TestList(t *testing.T) {
+	for _, l := range []struct {
+		v     string
+		keep  bool
+		elems []string
+	}{
+		{"a, b ,c", false, []string{"a", "b", "c"}},
+		{"a, b", true, []string{"a", " b"}},
+		{"", false, []string{}},
+		{`a,"b,c", " d "`, false, []string{"a", "b,c", " d "}},
+		{`"say \"hi\"",C:\dir`, false, []string{`say "hi"`, `C:\dir`}},
+		{`a\,b,c`, false, []string{"a,b", "c"}},
+		{`""`, false, []string{""}},
+		{"a,,b", false, []string{"a", "", "b"}},
+		{`"a,b";c, "d"x`, false, []string{`"a,b";c`, `"d"x`}},
+		{`a"b,c`, false, []string{`a"b`, "c"}},
+		{`"a`, false, []string{`"a`}},
+		{`a,"b\"`, false, []string{"a", `"b\"`}},
+	} {
+		elems, err := splitList(l.v, ",", l.keep)
+		if err != nil || strings.Join(elems, "|") != strings.Join(l.elems, "|") || len(elems) != len(l.elems) {
+			t.Errorf("splitList(%q) = %q, %v not equals %q", l.v, elems, err, l.elems)
+		}
+	}
+	for _, elems := range [][]string{{}, {""}, {"a", "b,c", " d", `"q"`, `x\`}, {"", ""}, {`a"b`, `"`}} {
+		v, err := joinList(elems, ",")
+		if err != nil {
+			t.Fatalf("joinList(%q) failed (%s)", elems, err.Error())
+		}
+		if got, err := splitList(v, ",", false); err != nil || len(got) != len(elems) || strings.Join(got, "|") != strings.Join(elems, "|") {
+			t.Errorf("splitList(joinList(%q) = %q) = %q, %v", elems, v, got, err)
+		}
+	}
+	c := New()
+	s := c.Add("core")
+	if err := s.SetStrings("l", []string{`a"b`}, ","); err != nil {
+		t.Fatalf("SetStrings() failed (%s)", err.Error())
+	}
+	if err := s.SetMap("m", map[string]string{"x": `x"y`}, ","); err != nil {
+		t.Fatalf("SetMap() failed (%s)", err.Error())
+	}
+	if l, err := s.Strings("l", ","); err != nil || len(l) != 1 || l[0] != `a"b` {
+		t.Errorf("Strings(\"l\") = %q, %v", l, err)
+	}
+	if m, err := s.Map("m", ",", "="); err != nil || m["x"] != `x"y` {
+		t.Errorf("Map(\"m\") = %q, %v", m, err)
+	}
+	c = parseString(t, "[core]\nm a: 1; b : 2\n")
+	if m, err := c.Get("core").Map("m", ";", ":"); err != nil || len(m) != 2 || m["b"] != "2" {
+		t.Errorf("Map(\"m\") = %v, %v", m, err)
+	}
+	var tc struct {
+		M map[string]int `goconf:"core:m:;::"`
+	}
+	if err := c.Unmarshal(&tc); err != nil || tc.M["a"] != 1 || tc.M["b"] != 2 {
+		t.Errorf("c.Unmarshal() = %v, %v", tc.M, err)
+	}
+}
+
+func TestMapFormat(t *testing.T) {
+	for _, f := range []struct {
+		format, delim, sep string
+	}{{"", ",", "="}, {",", ",", "="}, {";::", ";", ":"}, {";:", ";:", "="}, {",:=>", ",", "=>"}} {
+		if delim, sep := mapFormat(f.format); delim != f.delim || sep != f.sep {
+			t.Errorf("mapFormat(%q) = %q, %q not equals %q, %q", f.format, delim, sep, f.delim, f.sep)
+		}
+	}
+}
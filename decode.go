@@ -0,0 +1,648 @@
+package goconf
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueUnmarshaler is the interface implemented by types that can decode a
+// config value of themselves. The section and key of the value are given for
+// context, such as reading a related key.
+type ValueUnmarshaler interface {
+	UnmarshalGoconf(s *Section, key, value string) error
+}
+
+var (
+	valueUnmarshalerType = reflect.TypeOf((*ValueUnmarshaler)(nil)).Elem()
+	textUnmarshalerType  = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// An UnmarshalError describes a config value that could not be decoded into
+// a struct field.
+type UnmarshalError struct {
+	Field   string // field path, such as "Redis.Pool.Size"
+	Section string
+	Key     string
+	Value   string
+	Line    int // line in the parsed file, 0 if unknown
+	Err     error
+}
+
+func (e *UnmarshalError) Error() string {
+	line := ""
+	if e.Line > 0 {
+		line = fmt.Sprintf(" at %d", e.Line)
+	}
+	return fmt.Sprintf("key: \"%s\" in [%s]%s of field: %s invalid value %q: %s", e.Key, e.Section, line, e.Field, e.Value, e.Err)
+}
+
+func (e *UnmarshalError) Unwrap() error {
+	return e.Err
+}
+
+// An InvalidUnmarshalError describes an invalid argument passed to Unmarshal.
+// (The argument to Unmarshal must be a non-nil pointer to a struct.)
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "goconf: Unmarshal(nil)"
+	}
+	if e.Type.Kind() != reflect.Ptr {
+		return "goconf: Unmarshal(non-pointer " + e.Type.String() + ")"
+	}
+	if e.Type.Elem().Kind() != reflect.Struct {
+		return "goconf: Unmarshal(non-struct " + e.Type.String() + ")"
+	}
+	return "goconf: Unmarshal(nil " + e.Type.String() + ")"
+}
+
+// Unmarshal parses the goconf struct and stores the result in the value
+// pointed to by v.
+//
+// Struct values encode as goconf objects. Each exported struct field
+// becomes a member of the object unless
+//   - the field's tag is "-", or
+//   - the field is empty and its tag specifies the "omitempty" option.
+//
+// The empty values are false, 0, any
+// nil pointer or interface value, and any array, slice, map, or string of
+// length zero. The object's section and key string is the struct field name
+// but can be specified in the struct field's tag value. The "goconf" key in
+// the struct field's tag value is the key name, followed by an optional comma
+// and options. Examples:
+//
+//	// Field is ignored by this package.
+//	Field int `goconf:"-"`
+//
+//	// Field appears in goconf section "base" as key "myName".
+//	Field int `goconf:"base:myName"`
+//
+//	// Field appears in goconf section "base" as key "myName", the value split
+//	// by delimiter ",", see Section.Strings for quoting.
+//	Field []string `goconf:"base:myName:,"`
+//
+//	// Field appears in goconf section "base" as key "myName", the value split
+//	// by delimiter "," and key-value is splited by "=".
+//	Field map[int]string `goconf:"base:myName:,"`
+//
+//	// Field appears in goconf section "base" as key "myName", the value split
+//	// by delimiter ";" and key-value is splited by ":".
+//	Field map[string]int `goconf:"base:myName:;::"`
+//
+//	// Field appears in goconf section "base" as key "myName", nested lists
+//	// and maps take one delimiter per level separated by spaces, outermost
+//	// first, the value "a=1,2;b=3" gives {"a": [1 2], "b": [3]}.
+//	Field map[string][]int `goconf:"base:myName:;:= ,"`
+//
+//	// Field appears in goconf section "base" as key "myName", the value is
+//	// decoded by encoding/json, for any field type.
+//	Field []map[string]int `goconf:"base:myName:json"`
+//
+//	// Field appears in goconf section "base" as key "myName", the value
+//	// conver to time.Duration. When has extra tag "time", then goconf can
+//	// parse such "1h", "1h30m", "1.5s", "2d" config values, see
+//	// Section.Duration.
+//	//
+//	// Note the extra tag "time" only effect the int64 (time.Duration is int64)
+//	Field time.Duration `goconf:"base:myName:time"`
+//
+//	// Field appears in goconf section "base" as key "myName", when has extra
+//	// tag, then goconf can parse like "1gb", "1.5GB", "512MiB" config values,
+//	// see Section.MemSize.
+//	//
+//	// Note the extra tag "memory" only effect the integer kinds, a size that
+//	// does not fit the field is an error.
+//	Field int `goconf:"base:myName:memory"`
+//
+//	// Field appears in goconf section "base" as key "myName", when has extra
+//	// tag "strict", then an unknown boolean word is an error even if
+//	// Config.StrictBool is not set.
+//	Field bool `goconf:"base:myName:strict"`
+//
+//	// Field appears in goconf section "base" as key "myName", the value is
+//	// parsed in the layout after the key, or a layout name such as
+//	// "DateOnly". Without layout RFC3339 is used.
+//	Field time.Time `goconf:"base:myName:2006-01-02 15:04"`
+//
+//	// Field appears in goconf section "base" as key "myName", the value is
+//	// parsed by the parser registered for its type with RegisterParser, the
+//	// same parser is used for slice elements and map keys and values.
+//	Field Level `goconf:"base:myName"`
+//
+// Pointer fields are only allocated when the key exists, so a nil pointer
+// tells a missing key from a zero value. Arrays need exactly as many
+// elements as their length. An interface{} field holds the raw string, or
+// with the "infer" option an int64, float64 or bool if the value parses as
+// one:
+//
+//	// Field is nil unless goconf section "base" has key "myName".
+//	Field *int `goconf:"base:myName"`
+//
+//	// Field holds int64(1) for "1", true for "on", "abc" for "abc".
+//	Field interface{} `goconf:"base:myName:infer"`
+//
+// Types implementing ValueUnmarshaler or encoding.TextUnmarshaler, such as
+// a log level, decode their own values, as fields or as list and map
+// elements. Parsers registered with RegisterParser come first.
+//
+// A struct field, or pointer to struct, is mapped to a whole section, its
+// own fields are tagged by key only. Structs nest onto dotted sections and
+// a pointer is only allocated when its section exists, the fields of a
+// struct value are decoded from their defaults and validated even if its
+// section is missing:
+//
+//	type Redis struct {
+//		Addr    string        `goconf:"addr"`
+//		Timeout time.Duration `goconf:"timeout:time"`
+//		// section "redis.pool"
+//		Pool *Pool `goconf:"pool"`
+//	}
+//
+//	// Field appears as goconf section "redis".
+//	Field Redis `goconf:"redis"`
+//
+// A slice of structs is filled from the sections named after the tag and a
+// dot, in file order, and a map of structs is keyed by the rest of the
+// section name. A string field tagged ":section" is set to the name of the
+// section its struct is decoded from:
+//
+//	type Pool struct {
+//		Name string `goconf:":section"`
+//		Addr string `goconf:"addr"`
+//	}
+//
+//	// Field is filled from goconf sections "pool.a", "pool.b"...
+//	Field []Pool `goconf:"pool"`
+//
+//	// Field is filled from goconf sections "pool.a", "pool.b"... with
+//	// keys "a", "b"...
+//	Field map[string]*Pool `goconf:"pool"`
+//
+// The "default" struct tag is decoded when the key is missing, and the
+// "validate" struct tag lists rules checked after decoding. Unmarshal
+// reports every failed rule together as Errors of *ValidationError:
+//
+//	// Field is 30s if goconf section "base" has no key "myName".
+//	Field time.Duration `goconf:"base:myName:time" default:"30s"`
+//
+//	// Field must be set, from 1 to 65535.
+//	Field int `goconf:"base:myName" validate:"required,min=1,max=65535"`
+//
+//	// Field must be one of the words, or match the pattern which takes
+//	// the rest of the tag. min and max are lengths for strings, slices and
+//	// maps.
+//	Field string `goconf:"base:myName" validate:"oneof=debug|info|error"`
+//	Field string `goconf:"base:myName" validate:"max=64,pattern=^[a-z,]+$"`
+//
+// A value that cannot be decoded is reported as an *UnmarshalError naming
+// the field, section, key and line, wrapping the cause. Unmarshal stops at
+// the first one unless Config.CollectErrors is set.
+//
+// When Config.DisallowUnknown is set, the sections and keys of the config
+// that no struct field asks for are reported as *UnknownError, together with
+// the validate rules.
+//
+// An embedded struct without tag is flattened, its fields are decoded as if
+// they were fields of the outer struct, like encoding/json does.
+func (c *Config) Unmarshal(v interface{}) error {
+	vv := reflect.ValueOf(v)
+	if vv.Kind() != reflect.Ptr || vv.IsNil() || vv.Elem().Kind() != reflect.Struct {
+		return &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+	d := &decodeState{}
+	if c.DisallowUnknown {
+		d.known = map[string]map[string]bool{}
+	}
+	if err := c.unmarshalStruct(vv.Elem(), "", "", d); err != nil {
+		return err
+	}
+	if d.known != nil {
+		c.unknown(d)
+	}
+	return d.errs.err()
+}
+
+// decodeState is the state of one Unmarshal call.
+type decodeState struct {
+	// failed validate rules and, if CollectErrors, field errors
+	errs Errors
+	// section:key:true of the struct fields, "" key for a section struct,
+	// nil unless DisallowUnknown
+	known map[string]map[string]bool
+}
+
+// use mark the key of section as known, "" for the section itself.
+func (d *decodeState) use(section, key string) {
+	if d.known == nil {
+		return
+	}
+	keys, ok := d.known[section]
+	if !ok {
+		keys = map[string]bool{}
+		d.known[section] = keys
+	}
+	if key != "" {
+		keys[key] = true
+	}
+}
+
+// unmarshalStruct decode the fields of the struct rv mapped to section, ""
+// is the top-level struct whose fields name their section in the tag. path
+// is the field path of rv, such as "Redis.".
+func (c *Config) unmarshalStruct(rv reflect.Value, section, path string, d *decodeState) error {
+	p := planOf(rv.Type(), section != "")
+	if p.err != nil {
+		return p.err
+	}
+	for i := range p.fields {
+		fp := &p.fields[i]
+		vf := rv.Field(fp.index)
+		tf := fp.field
+		switch fp.kind {
+		case fieldEmbedded:
+			if vf.Kind() == reflect.Ptr {
+				if vf.IsNil() {
+					if !vf.CanSet() {
+						// unexported embedded pointer
+						continue
+					}
+					vf.Set(reflect.New(tf.Type.Elem()))
+				}
+				vf = vf.Elem()
+			}
+			if err := c.unmarshalStruct(vf, section, path, d); err != nil {
+				return err
+			}
+			continue
+		case fieldSectionName:
+			vf.SetString(section)
+			continue
+		case fieldSection, fieldSections:
+			name := fp.name
+			if section != "" {
+				name = section + "." + name
+			}
+			if fp.kind == fieldSections {
+				if err := c.unmarshalSections(vf, name, path+tf.Name, d); err != nil {
+					return err
+				}
+				continue
+			}
+			d.use(name, "")
+			if vf.Kind() == reflect.Ptr {
+				if vf.IsNil() {
+					if !c.hasSection(name) {
+						// no config section, leave the pointer nil
+						continue
+					}
+					vf.Set(reflect.New(tf.Type.Elem()))
+				}
+				vf = vf.Elem()
+			}
+			// a missing section still gets its defaults and rules
+			if err := c.unmarshalStruct(vf, name, path+tf.Name+".", d); err != nil {
+				return err
+			}
+			continue
+		}
+		name, key, format := section, fp.key, fp.format
+		if section == "" {
+			name = fp.name
+		}
+		d.use(name, key)
+		s := c.Get(name)
+		value, ok := s.Lookup(key)
+		if !ok && fp.hasDef {
+			value, ok = fp.def, true
+		}
+		if ok {
+			var err error
+			if fp.parser != nil {
+				var vv reflect.Value
+				if vv, err = fp.parser(c, value); err == nil {
+					vf.Set(vv)
+				}
+			} else {
+				err = c.decodeField(s, key, format, value, vf, tf)
+			}
+			if err != nil {
+				uerr := &UnmarshalError{Field: path + tf.Name, Section: name, Key: key, Value: value, Line: s.Line(key), Err: err}
+				if !c.CollectErrors {
+					return uerr
+				}
+				d.errs = append(d.errs, uerr)
+				continue
+			}
+		}
+		if fp.rules != nil {
+			if err := c.validate(s, name, key, format, value, ok, vf, tf, path+tf.Name, fp.rules, &d.errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodeField decode the value of the key of section s into the struct
+// field vf, format is the tag option. s is nil for a default value of a
+// missing section.
+func (c *Config) decodeField(s *Section, key, format, value string, vf reflect.Value, tf reflect.StructField) error {
+	// allocate pointer only when the key exists
+	if vf.Kind() == reflect.Ptr && lookupParser(vf.Type()) == nil && format != "json" {
+		ev := reflect.New(vf.Type().Elem())
+		if err := c.decodeField(s, key, format, value, ev.Elem(), tf); err != nil {
+			return err
+		}
+		vf.Set(ev)
+		return nil
+	}
+	switch {
+	case format == "json":
+		if err := json.Unmarshal([]byte(value), vf.Addr().Interface()); err != nil {
+			return err
+		}
+	case format == "memory" && (isInt(vf.Kind()) || isUint(vf.Kind())):
+		// parse memory size
+		if tmp, err := parseMemory(value, c.legacyMemSize()); err != nil {
+			return err
+		} else if isUint(vf.Kind()) {
+			if vf.OverflowUint(tmp) {
+				return errors.New(fmt.Sprintf("memory size: %s overflows struct field: %s", value, tf.Name))
+			}
+			vf.SetUint(tmp)
+		} else {
+			if tmp > math.MaxInt64 || vf.OverflowInt(int64(tmp)) {
+				return errors.New(fmt.Sprintf("memory size: %s overflows struct field: %s", value, tf.Name))
+			}
+			vf.SetInt(int64(tmp))
+		}
+	case format == "strict" && vf.Kind() == reflect.Bool:
+		if tmp, err := parseBool(value, true); err != nil {
+			return err
+		} else {
+			vf.SetBool(tmp)
+		}
+	case format == "infer" && vf.Kind() == reflect.Interface && vf.NumMethod() == 0:
+		vf.Set(reflect.ValueOf(c.inferValue(value)))
+	case format != "" && vf.Type() == timeType:
+		// parse time in layout
+		if tmp, err := time.Parse(timeLayout(format), value); err != nil {
+			return err
+		} else {
+			vf.Set(reflect.ValueOf(tmp))
+		}
+	case format == "time" && vf.Kind() == reflect.Int64:
+		// parse time
+		if tmp, err := parseTime(value, c.durationUnit()); err != nil {
+			return err
+		} else {
+			vf.SetInt(int64(tmp))
+		}
+	case format != "" && vf.Kind() == reflect.Int64:
+		return errors.New(fmt.Sprintf("unknown tag: %s in struct field: %s (support tags: \"memory\", \"time\")", format, tf.Name))
+	case format != "" && (isInt(vf.Kind()) || isUint(vf.Kind())):
+		return errors.New(fmt.Sprintf("unknown tag: %s in struct field: %s (support tags: \"memory\")", format, tf.Name))
+	default:
+		if err := c.decodeValue(s, key, vf, value, listLevels(format)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmarshalSections decode every section named prefix.<name> into the
+// slice, in file order, or the map keyed by <name>. Sub-sections of the
+// matched sections are left to their nested structs. path is the field path
+// of vf.
+func (c *Config) unmarshalSections(vf reflect.Value, prefix, path string, d *decodeState) error {
+	t := vf.Type()
+	et := t.Elem()
+	var (
+		slice reflect.Value
+		m     reflect.Value
+	)
+	if t.Kind() == reflect.Slice {
+		slice = reflect.MakeSlice(t, 0, 0)
+	} else {
+		m = reflect.MakeMap(t)
+	}
+	for _, name := range c.dataOrder {
+		if !strings.HasPrefix(name, prefix+".") {
+			continue
+		}
+		suffix := name[len(prefix)+1:]
+		if suffix == "" || strings.Contains(suffix, ".") {
+			continue
+		}
+		ev := reflect.New(indirectType(et))
+		epath := fmt.Sprintf("%s[%s].", path, suffix)
+		if slice.IsValid() {
+			epath = fmt.Sprintf("%s[%d].", path, slice.Len())
+		}
+		d.use(name, "")
+		if err := c.unmarshalStruct(ev.Elem(), name, epath, d); err != nil {
+			return err
+		}
+		if et.Kind() != reflect.Ptr {
+			ev = ev.Elem()
+		}
+		if slice.IsValid() {
+			slice = reflect.Append(slice, ev)
+		} else {
+			m.SetMapIndex(reflect.ValueOf(suffix).Convert(t.Key()), ev)
+		}
+	}
+	if slice.IsValid() {
+		if slice.Len() > 0 {
+			vf.Set(slice)
+		}
+	} else if m.Len() > 0 {
+		vf.Set(m)
+	}
+	return nil
+}
+
+// isSectionsType return true if t is a slice of structs or a map of structs
+// keyed by string, which are decoded from prefixed sections.
+func isSectionsType(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice && (t.Kind() != reflect.Map || t.Key().Kind() != reflect.String) {
+		return false
+	}
+	et := indirectType(t.Elem())
+	return et.Kind() == reflect.Struct && !isValueType(et)
+}
+
+// hasSection return true if the section or one of its dotted sub-sections
+// exists.
+func (c *Config) hasSection(name string) bool {
+	if _, ok := c.data[name]; ok {
+		return true
+	}
+	for _, k := range c.dataOrder {
+		if strings.HasPrefix(k, name+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// indirectType return the element type of a pointer type.
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// isValueType return true if t is decoded from a single value, such as
+// time.Time, rather than mapped to a section.
+func isValueType(t reflect.Type) bool {
+	pt := reflect.PtrTo(t)
+	return lookupParser(t) != nil || lookupParser(pt) != nil ||
+		pt.Implements(valueUnmarshalerType) || pt.Implements(textUnmarshalerType)
+}
+
+// listLevels split the delimiter spec of a list or map tag option into one
+// spec per nesting level, outermost first. Levels are separated by spaces,
+// a spec without spaces around delimiters, such as " ", is a single level.
+func listLevels(format string) []string {
+	levels := strings.Fields(format)
+	if len(levels) <= 1 {
+		return []string{format}
+	}
+	return levels
+}
+
+// decodeValue decode the raw value of the key of section s into v by the
+// type of v. Lists and maps are split by the first level of levels, their
+// elements decoded with the rest, a missing level means Delim and "=".
+func (c *Config) decodeValue(s *Section, key string, v reflect.Value, value string, levels []string) error {
+	t := v.Type()
+	if p := lookupParser(t); p != nil {
+		vv, err := p(c, value)
+		if err != nil {
+			return err
+		}
+		v.Set(vv)
+		return nil
+	}
+	if v.CanAddr() {
+		switch u := v.Addr().Interface().(type) {
+		case ValueUnmarshaler:
+			return u.UnmarshalGoconf(s, key, value)
+		case encoding.TextUnmarshaler:
+			return u.UnmarshalText([]byte(value))
+		}
+	}
+	format, rest := "", []string(nil)
+	if len(levels) > 0 {
+		format, rest = levels[0], levels[1:]
+	}
+	switch t.Kind() {
+	case reflect.Slice:
+		delim := Delim
+		if format != "" {
+			delim = format
+		}
+		strs, err := splitList(value, delim, c.keepListSpace())
+		if err != nil {
+			return err
+		}
+		sli := reflect.MakeSlice(t, len(strs), len(strs))
+		for i, str := range strs {
+			if err = c.decodeValue(s, key, sli.Index(i), str, rest); err != nil {
+				return err
+			}
+		}
+		v.Set(sli)
+	case reflect.Array:
+		delim := Delim
+		if format != "" {
+			delim = format
+		}
+		strs, err := splitList(value, delim, c.keepListSpace())
+		if err != nil {
+			return err
+		}
+		if len(strs) != t.Len() {
+			return errors.New(fmt.Sprintf("array: %s has %d elements, type: %s needs %d", value, len(strs), t, t.Len()))
+		}
+		arr := reflect.New(t).Elem()
+		for i, str := range strs {
+			if err = c.decodeValue(s, key, arr.Index(i), str, rest); err != nil {
+				return err
+			}
+		}
+		v.Set(arr)
+	case reflect.Ptr:
+		ev := reflect.New(t.Elem())
+		if err := c.decodeValue(s, key, ev.Elem(), value, levels); err != nil {
+			return err
+		}
+		v.Set(ev)
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return errors.New(fmt.Sprintf("cannot unmarshall non-empty interface type: %s", t))
+		}
+		// the raw string, see inferValue for the "infer" option
+		v.Set(reflect.ValueOf(value))
+	case reflect.Map:
+		delim, sep := mapFormat(format)
+		strs, err := splitList(value, delim, c.keepListSpace())
+		if err != nil {
+			return err
+		}
+		m := reflect.MakeMapWithSize(t, len(strs))
+		for _, str := range strs {
+			k, e, err := splitPair(str, sep, c.keepListSpace())
+			if err != nil {
+				return err
+			}
+			vk := reflect.New(t.Key()).Elem()
+			if err = c.decodeValue(s, key, vk, k, nil); err != nil {
+				return err
+			}
+			vv := reflect.New(t.Elem()).Elem()
+			if err = c.decodeValue(s, key, vv, e, rest); err != nil {
+				return err
+			}
+			m.SetMapIndex(vk, vv)
+		}
+		v.Set(m)
+	default:
+		if _, ok := kindTypes[t.Kind()]; !ok {
+			return errors.New(fmt.Sprintf("cannot unmarshall unsuported kind: %s of type: %s", t.Kind(), t))
+		}
+		vv, err := parseValue(c, t, value)
+		if err != nil {
+			return err
+		}
+		v.Set(vv)
+	}
+	return nil
+}
+
+// inferValue return v as an int64, a float64 or a bool if it parses as one,
+// in this order, else the string itself.
+func (c *Config) inferValue(v string) interface{} {
+	if d, err := strconv.ParseInt(v, c.intBase(), 64); err == nil {
+		return d
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	if b, err := parseBool(v, true); err == nil {
+		return b
+	}
+	return v
+}
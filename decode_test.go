@@ -0,0 +1,443 @@
+package goconf
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnmarshalNested(t *testing.T) {
+	c := parseString(t, `[core]
+groups a=1,2;b=3
+matrix a,b;c
+quoted "a,b",c;d
+maps a=1,b=2;c=3
+words hello world
+json [{"a":1},{"b":2}]
+`)
+	var tc struct {
+		Groups map[string][]int  `goconf:"core:groups:;:= ,"`
+		Matrix [][]string        `goconf:"core:matrix:; ,"`
+		Quoted [][]string        `goconf:"core:quoted:;"`
+		Maps   []map[string]int  `goconf:"core:maps:; ,"`
+		Words  []string          `goconf:"core:words: "`
+		JSON   []map[string]int  `goconf:"core:json:json"`
+		Fields map[string]string `goconf:"core:none:json"`
+	}
+	if err := c.Unmarshal(&tc); err != nil {
+		t.Fatalf("c.Unmarshal() failed (%s)", err.Error())
+	}
+	if len(tc.Groups) != 2 || len(tc.Groups["a"]) != 2 || tc.Groups["a"][1] != 2 || tc.Groups["b"][0] != 3 {
+		t.Errorf("Groups %v", tc.Groups)
+	}
+	if len(tc.Matrix) != 2 || len(tc.Matrix[0]) != 2 || tc.Matrix[1][0] != "c" {
+		t.Errorf("Matrix %q", tc.Matrix)
+	}
+	if len(tc.Quoted) != 2 || len(tc.Quoted[0]) != 2 || tc.Quoted[0][0] != "a,b" {
+		t.Errorf("Quoted %q", tc.Quoted)
+	}
+	if len(tc.Maps) != 2 || tc.Maps[0]["b"] != 2 || tc.Maps[1]["c"] != 3 {
+		t.Errorf("Maps %v", tc.Maps)
+	}
+	if len(tc.Words) != 2 || tc.Words[1] != "world" {
+		t.Errorf("Words %q", tc.Words)
+	}
+	if len(tc.JSON) != 2 || tc.JSON[1]["b"] != 2 {
+		t.Errorf("JSON %v", tc.JSON)
+	}
+	var bad struct {
+		Words []int `goconf:"core:json:json"`
+	}
+	if err := c.Unmarshal(&bad); err == nil {
+		t.Errorf("c.Unmarshal() invalid json no error")
+	}
+}
+
+type testPool struct {
+	Size int `goconf:"size"`
+}
+
+type testRedis struct {
+	Addr    string        `goconf:"addr"`
+	Timeout time.Duration `goconf:"timeout:time"`
+	Pool    *testPool     `goconf:"pool"`
+	Idle    *testPool     `goconf:"idle"`
+}
+
+type testBase struct {
+	ID int `goconf:"core:id"`
+}
+
+func TestUnmarshalSection(t *testing.T) {
+	c := parseString(t, `[core]
+id 1
+[redis]
+addr 127.0.0.1:6379
+timeout 1s
+[redis.pool]
+size 8
+[cache]
+addr 127.0.0.1:11211
+`)
+	var tc struct {
+		testBase
+		Redis testRedis  `goconf:"redis"`
+		Cache *testRedis `goconf:"cache"`
+		None  *testRedis `goconf:"none"`
+		Start time.Time  `goconf:"core:none"`
+	}
+	if err := c.Unmarshal(&tc); err != nil {
+		t.Fatalf("c.Unmarshal() failed (%s)", err.Error())
+	}
+	if tc.ID != 1 {
+		t.Errorf("embedded ID = %d", tc.ID)
+	}
+	if tc.Redis.Addr != "127.0.0.1:6379" || tc.Redis.Timeout != time.Second {
+		t.Errorf("Redis = %+v", tc.Redis)
+	}
+	if tc.Redis.Pool == nil || tc.Redis.Pool.Size != 8 {
+		t.Errorf("Redis.Pool = %+v", tc.Redis.Pool)
+	}
+	if tc.Redis.Idle != nil {
+		t.Errorf("Redis.Idle allocated without section")
+	}
+	if tc.Cache == nil || tc.Cache.Addr != "127.0.0.1:11211" || tc.Cache.Pool != nil {
+		t.Errorf("Cache = %+v", tc.Cache)
+	}
+	if tc.None != nil {
+		t.Errorf("None allocated without section")
+	}
+}
+
+type testUpstream struct {
+	Name   string    `goconf:":section"`
+	Addr   string    `goconf:"addr"`
+	Weight int       `goconf:"weight"`
+	Pool   *testPool `goconf:"pool"`
+}
+
+func TestUnmarshalSections(t *testing.T) {
+	c := parseString(t, `[pool.b]
+addr 10.0.0.2
+weight 2
+[pool.b.pool]
+size 4
+[pool]
+addr ignored
+[pool.a]
+addr 10.0.0.1
+weight 1
+`)
+	var tc struct {
+		List  []testUpstream           `goconf:"pool"`
+		Map   map[string]*testUpstream `goconf:"pool"`
+		Empty []testUpstream           `goconf:"none"`
+	}
+	if err := c.Unmarshal(&tc); err != nil {
+		t.Fatalf("c.Unmarshal() failed (%s)", err.Error())
+	}
+	if len(tc.List) != 2 || tc.List[0].Name != "pool.b" || tc.List[1].Addr != "10.0.0.1" {
+		t.Fatalf("List = %+v", tc.List)
+	}
+	if tc.List[0].Pool == nil || tc.List[0].Pool.Size != 4 || tc.List[1].Pool != nil {
+		t.Errorf("List pools = %+v, %+v", tc.List[0].Pool, tc.List[1].Pool)
+	}
+	if len(tc.Map) != 2 || tc.Map["a"] == nil || tc.Map["a"].Weight != 1 || tc.Map["b"].Name != "pool.b" {
+		t.Errorf("Map = %v", tc.Map)
+	}
+	if tc.Empty != nil {
+		t.Errorf("Empty = %v", tc.Empty)
+	}
+}
+
+func TestUnmarshalPointer(t *testing.T) {
+	c := parseString(t, `[core]
+port 0
+name goconf
+buf 1KiB
+ip 10,0,0,1
+short 1,2
+raw on
+num 1.5
+word on
+ints 1,2
+`)
+	var tc struct {
+		Port  *int          `goconf:"core:port"`
+		Name  *string       `goconf:"core:name"`
+		None  *int          `goconf:"core:none"`
+		Buf   *int64        `goconf:"core:buf:memory"`
+		IP    [4]byte       `goconf:"core:ip"`
+		Raw   interface{}   `goconf:"core:raw"`
+		Num   interface{}   `goconf:"core:num:infer"`
+		Word  interface{}   `goconf:"core:word:infer"`
+		Ints  []*int        `goconf:"core:ints"`
+		Elems []interface{} `goconf:"core:ints"`
+	}
+	if err := c.Unmarshal(&tc); err != nil {
+		t.Fatalf("c.Unmarshal() failed (%s)", err.Error())
+	}
+	if tc.Port == nil || *tc.Port != 0 || tc.Name == nil || *tc.Name != "goconf" || tc.None != nil {
+		t.Errorf("Port %v, Name %v, None %v", tc.Port, tc.Name, tc.None)
+	}
+	if tc.Buf == nil || *tc.Buf != KiB {
+		t.Errorf("Buf %v", tc.Buf)
+	}
+	if tc.IP != [4]byte{10, 0, 0, 1} {
+		t.Errorf("IP %v", tc.IP)
+	}
+	if tc.Raw != "on" || tc.Num != 1.5 || tc.Word != true {
+		t.Errorf("Raw %v, Num %v, Word %v", tc.Raw, tc.Num, tc.Word)
+	}
+	if len(tc.Ints) != 2 || *tc.Ints[1] != 2 || len(tc.Elems) != 2 || tc.Elems[0] != "1" {
+		t.Errorf("Ints %v, Elems %v", tc.Ints, tc.Elems)
+	}
+	var bad struct {
+		IP [4]byte `goconf:"core:short"`
+	}
+	if err := c.Unmarshal(&bad); err == nil {
+		t.Errorf("c.Unmarshal() short array no error")
+	}
+	mc, err := Marshal(&tc)
+	if err != nil {
+		t.Fatalf("Marshal() failed (%s)", err.Error())
+	}
+	if v, _ := mc.Get("core").String("ip"); v != "10,0,0,1" {
+		t.Errorf("Marshal() ip = %q", v)
+	}
+	if mc.Get("core").Has("none") {
+		t.Errorf("Marshal() wrote nil pointer")
+	}
+	if v, _ := mc.Get("core").String("buf"); v != "1KiB" {
+		t.Errorf("Marshal() buf = %q", v)
+	}
+}
+
+type testCompress int
+
+func (t *testCompress) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "none":
+		*t = 0
+	case "gzip":
+		*t = 1
+	default:
+		return errors.New("unknown compression: " + string(b))
+	}
+	return nil
+}
+
+func (t testCompress) MarshalText() ([]byte, error) {
+	if t == 1 {
+		return []byte("gzip"), nil
+	}
+	return []byte("none"), nil
+}
+
+// testRate is "n/unit" with the burst read from the key "<key>_burst".
+type testRate struct {
+	N     int
+	Per   string
+	Burst int64
+}
+
+func (r *testRate) UnmarshalGoconf(s *Section, key, value string) error {
+	n, per, ok := strings.Cut(value, "/")
+	if !ok {
+		return errors.New("rate must be n/unit")
+	}
+	var err error
+	if r.N, err = strconv.Atoi(n); err != nil {
+		return err
+	}
+	r.Per = per
+	r.Burst, err = s.IntOr(key+"_burst", int64(r.N))
+	return err
+}
+
+func (r testRate) MarshalGoconf(s *Section, key string) (string, error) {
+	s.SetInt(key+"_burst", r.Burst)
+	return strconv.Itoa(r.N) + "/" + r.Per, nil
+}
+
+func TestUnmarshalText(t *testing.T) {
+	c := parseString(t, `[core]
+compress gzip
+list none,gzip
+rate 100/s
+rate_burst 200
+`)
+	var tc struct {
+		Compress testCompress            `goconf:"core:compress"`
+		List     []testCompress          `goconf:"core:list"`
+		Map      map[string]testCompress `goconf:"core:none"`
+		Rate     testRate                `goconf:"core:rate"`
+	}
+	if err := c.Unmarshal(&tc); err != nil {
+		t.Fatalf("c.Unmarshal() failed (%s)", err.Error())
+	}
+	if tc.Compress != 1 || len(tc.List) != 2 || tc.List[0] != 0 || tc.List[1] != 1 {
+		t.Errorf("Compress %v, List %v", tc.Compress, tc.List)
+	}
+	if tc.Rate.N != 100 || tc.Rate.Per != "s" || tc.Rate.Burst != 200 {
+		t.Errorf("Rate %+v", tc.Rate)
+	}
+	mc, err := Marshal(&tc)
+	if err != nil {
+		t.Fatalf("Marshal() failed (%s)", err.Error())
+	}
+	s := mc.Get("core")
+	if v, _ := s.String("list"); v != "none,gzip" {
+		t.Errorf("Marshal() list = %q", v)
+	}
+	if v, _ := s.String("rate"); v != "100/s" {
+		t.Errorf("Marshal() rate = %q", v)
+	}
+	if v, _ := s.Int("rate_burst"); v != 200 {
+		t.Errorf("Marshal() rate_burst = %d", v)
+	}
+	var bad struct {
+		Compress testCompress `goconf:"core:rate"`
+	}
+	if err := c.Unmarshal(&bad); err == nil {
+		t.Errorf("c.Unmarshal() unknown compression no error")
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	c := parseString(t, `[core]
+port x
+
+timeout 1y
+[pool.a]
+size y
+`)
+	var tc struct {
+		Port    int                      `goconf:"core:port"`
+		Timeout time.Duration            `goconf:"core:timeout" validate:"required"`
+		Pools   map[string]*testUpstream `goconf:"pool"`
+		Pool    struct {
+			Size int `goconf:"size" validate:"min=1"`
+		} `goconf:"pool.a"`
+	}
+	err := c.Unmarshal(&tc)
+	var uerr *UnmarshalError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("c.Unmarshal() error %v not an UnmarshalError", err)
+	}
+	if uerr.Field != "Port" || uerr.Section != "core" || uerr.Key != "port" || uerr.Value != "x" || uerr.Line != 2 {
+		t.Errorf("UnmarshalError %+v", uerr)
+	}
+	var nerr *strconv.NumError
+	if !errors.As(err, &nerr) {
+		t.Errorf("c.Unmarshal() error %v does not wrap the cause", err)
+	}
+	c.CollectErrors = true
+	err = c.Unmarshal(&tc)
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("c.Unmarshal() errors:\n%v", err)
+	}
+	want := []string{"Port", "Timeout", "Pool.Size"}
+	for i, e := range errs {
+		if !errors.As(e, &uerr) || uerr.Field != want[i] {
+			t.Errorf("error %d = %v, want field %s", i, e, want[i])
+		}
+	}
+	if uerr.Line != 6 || !strings.Contains(uerr.Error(), `key: "size" in [pool.a] at 6 of field: Pool.Size`) {
+		t.Errorf("UnmarshalError %v", uerr)
+	}
+}
+
+type testBench struct {
+	ID      int               `goconf:"core:id"`
+	Name    string            `goconf:"core:name"`
+	Arr     []string          `goconf:"core:arr:,"`
+	M       map[string]int    `goconf:"core:m:,"`
+	Timeout time.Duration     `goconf:"core:timeout:time" default:"30s"`
+	Buf     int64             `goconf:"core:buf:memory"`
+	Debug   bool              `goconf:"core:debug"`
+	Redis   testRedis         `goconf:"redis"`
+	Pools   []testUpstream    `goconf:"pool"`
+	Extra   map[string]string `goconf:"core:extra:json"`
+}
+
+const testBenchConf = `[core]
+id 1
+name goconf
+arr a,b,c
+m a=1,b=2
+buf 1GiB
+debug on
+extra {"a":"b"}
+[redis]
+addr 127.0.0.1:6379
+timeout 1s
+[redis.pool]
+size 8
+[pool.a]
+addr 10.0.0.1
+[pool.b]
+addr 10.0.0.2
+`
+
+func BenchmarkUnmarshal(b *testing.B) {
+	c := New()
+	if err := c.ParseReader(strings.NewReader(testBenchConf)); err != nil {
+		b.Fatalf("c.ParseReader() failed (%s)", err.Error())
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var tc testBench
+		if err := c.Unmarshal(&tc); err != nil {
+			b.Fatalf("c.Unmarshal() failed (%s)", err.Error())
+		}
+	}
+}
+
+// BenchmarkNewPlan measures building the plans of the structs decoded by
+// BenchmarkUnmarshal, the tag parsing the plan cache saves on every call.
+func BenchmarkNewPlan(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		newPlan(reflect.TypeOf(testBench{}), false)
+		newPlan(reflect.TypeOf(testRedis{}), true)
+		newPlan(reflect.TypeOf(testPool{}), true)
+		newPlan(reflect.TypeOf(testUpstream{}), true)
+	}
+}
+
+type testUpper string
+
+func TestUnmarshalPlanCache(t *testing.T) {
+	c := parseString(t, "[core]\nname goconf\n")
+	var tc struct {
+		Name testUpper `goconf:"core:name"`
+	}
+	if err := c.Unmarshal(&tc); err != nil || tc.Name != "goconf" {
+		t.Fatalf("c.Unmarshal() = %q, %v", tc.Name, err)
+	}
+	// a new parser replaces the cached plan
+	RegisterParser(func(v string) (testUpper, error) { return testUpper(strings.ToUpper(v)), nil })
+	if err := c.Unmarshal(&tc); err != nil || tc.Name != "GOCONF" {
+		t.Errorf("c.Unmarshal() = %q, %v", tc.Name, err)
+	}
+}
+
+func TestUnmarshalErrorMessage(t *testing.T) {
+	c := parseString(t, "[r]\n\nday 2024-13-01\n")
+	var tc struct {
+		Day time.Time `goconf:"r:day:DateOnly"`
+	}
+	err := c.Unmarshal(&tc)
+	var verr *ValueError
+	if err == nil || errors.As(err, &verr) {
+		t.Fatalf("c.Unmarshal() error = %v", err)
+	}
+	if msg := err.Error(); !strings.HasPrefix(msg, `key: "day" in [r] at 3 of field: Day invalid value "2024-13-01": parsing time`) {
+		t.Errorf("c.Unmarshal() error = %s", msg)
+	}
+}
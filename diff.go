@@ -0,0 +1,270 @@
+package goconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ChangeKind is the kind of a change between two configs.
+type ChangeKind int
+
+const (
+	ChangeAdded ChangeKind = iota + 1
+	ChangeRemoved
+	ChangeValue
+	ChangeComment
+)
+
+var changeKinds = map[ChangeKind]string{
+	ChangeAdded:   "added",
+	ChangeRemoved: "removed",
+	ChangeValue:   "changed",
+	ChangeComment: "comment",
+}
+
+func (k ChangeKind) String() string {
+	if s, ok := changeKinds[k]; ok {
+		return s
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// MarshalText encode the kind as its name in the JSON rendering.
+func (k ChangeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText decode the kind from its name.
+func (k *ChangeKind) UnmarshalText(text []byte) error {
+	for kind, s := range changeKinds {
+		if s == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("unknown change kind: %s", text))
+}
+
+// SectionChange describes an added or removed section, or a section whose
+// comments changed.
+type SectionChange struct {
+	Section     string     `json:"section"`
+	Kind        ChangeKind `json:"kind"`
+	OldComments []string   `json:"old_comments,omitempty"`
+	NewComments []string   `json:"new_comments,omitempty"`
+}
+
+// KeyChange describes an added, removed or changed key, or a key whose
+// comments changed. Keys of added and removed sections are reported too.
+type KeyChange struct {
+	Section     string     `json:"section"`
+	Key         string     `json:"key"`
+	Kind        ChangeKind `json:"kind"`
+	Old         string     `json:"old"`
+	New         string     `json:"new"`
+	OldComments []string   `json:"old_comments,omitempty"`
+	NewComments []string   `json:"new_comments,omitempty"`
+}
+
+// ConfigDiff is the structural difference between two configs.
+type ConfigDiff struct {
+	From     string          `json:"from"`
+	To       string          `json:"to"`
+	Sections []SectionChange `json:"sections"`
+	Keys     []KeyChange     `json:"keys"`
+	order    []string        // sections with changes
+	spliter  string
+}
+
+// Diff compare config a with config b. A nil config is treated as empty.
+func Diff(a, b *Config) *ConfigDiff {
+	if a == nil {
+		a = New()
+	}
+	if b == nil {
+		b = New()
+	}
+	d := &ConfigDiff{From: a.file, To: b.file, Sections: []SectionChange{}, Keys: []KeyChange{}, spliter: b.Spliter}
+	for _, name := range a.dataOrder {
+		as := a.data[name]
+		bs, ok := b.data[name]
+		if !ok {
+			d.addSection(SectionChange{Section: name, Kind: ChangeRemoved, OldComments: as.comments})
+			for _, k := range as.dataOrder {
+				d.Keys = append(d.Keys, KeyChange{Section: name, Key: k, Kind: ChangeRemoved, Old: as.data[k], OldComments: as.dataComments[k]})
+			}
+			continue
+		}
+		if !equalComments(as.comments, bs.comments) {
+			d.addSection(SectionChange{Section: name, Kind: ChangeComment, OldComments: as.comments, NewComments: bs.comments})
+		}
+		d.diffKeys(as, bs)
+	}
+	for _, name := range b.dataOrder {
+		if _, ok := a.data[name]; ok {
+			continue
+		}
+		bs := b.data[name]
+		d.addSection(SectionChange{Section: name, Kind: ChangeAdded, NewComments: bs.comments})
+		for _, k := range bs.dataOrder {
+			d.Keys = append(d.Keys, KeyChange{Section: name, Key: k, Kind: ChangeAdded, New: bs.data[k], NewComments: bs.dataComments[k]})
+		}
+	}
+	return d
+}
+
+// diffKeys compare the keys of two sections with the same name.
+func (d *ConfigDiff) diffKeys(as, bs *Section) {
+	changes := []KeyChange{}
+	for _, k := range as.dataOrder {
+		ov, ok := as.data[k]
+		if !ok {
+			// deleted key of a layer
+			continue
+		}
+		nv, ok := bs.data[k]
+		kc := KeyChange{Section: as.Name, Key: k, Old: ov, New: nv, OldComments: as.dataComments[k], NewComments: bs.dataComments[k]}
+		if !ok {
+			kc.Kind = ChangeRemoved
+		} else if ov != nv {
+			kc.Kind = ChangeValue
+		} else if !equalComments(kc.OldComments, kc.NewComments) {
+			kc.Kind = ChangeComment
+		} else {
+			continue
+		}
+		changes = append(changes, kc)
+	}
+	for _, k := range bs.dataOrder {
+		if _, ok := bs.data[k]; !ok {
+			continue
+		}
+		if _, ok := as.data[k]; !ok {
+			changes = append(changes, KeyChange{Section: bs.Name, Key: k, Kind: ChangeAdded, New: bs.data[k], NewComments: bs.dataComments[k]})
+		}
+	}
+	if len(changes) > 0 {
+		d.section(as.Name)
+		d.Keys = append(d.Keys, changes...)
+	}
+}
+
+// addSection record a section change.
+func (d *ConfigDiff) addSection(sc SectionChange) {
+	d.section(sc.Section)
+	d.Sections = append(d.Sections, sc)
+}
+
+// section remember the order of the changed sections for String.
+func (d *ConfigDiff) section(name string) {
+	if len(d.order) == 0 || d.order[len(d.order)-1] != name {
+		d.order = append(d.order, name)
+	}
+}
+
+// equalComments compare two comment blocks ignoring blank lines.
+func equalComments(a, b []string) bool {
+	i, j := 0, 0
+	for {
+		for i < len(a) && a[i] == "" {
+			i++
+		}
+		for j < len(b) && b[j] == "" {
+			j++
+		}
+		if i == len(a) || j == len(b) {
+			return i == len(a) && j == len(b)
+		}
+		if a[i] != b[j] {
+			return false
+		}
+		i++
+		j++
+	}
+}
+
+// Empty return true if the configs are identical.
+func (d *ConfigDiff) Empty() bool {
+	return len(d.Sections) == 0 && len(d.Keys) == 0
+}
+
+// String render the diff as unified text, one hunk per changed section.
+func (d *ConfigDiff) String() string {
+	var (
+		buf      bytes.Buffer
+		from, to = d.From, d.To
+	)
+	if d.Empty() {
+		return ""
+	}
+	if from == "" {
+		from = "a"
+	}
+	if to == "" {
+		to = "b"
+	}
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", from, to)
+	for _, name := range d.order {
+		header := false
+		for _, sc := range d.Sections {
+			if sc.Section != name {
+				continue
+			}
+			header = true
+			switch sc.Kind {
+			case ChangeAdded:
+				writeLines(&buf, "+", sc.NewComments)
+				fmt.Fprintf(&buf, "+[%s]\n", name)
+			case ChangeRemoved:
+				writeLines(&buf, "-", sc.OldComments)
+				fmt.Fprintf(&buf, "-[%s]\n", name)
+			default:
+				writeLines(&buf, "-", sc.OldComments)
+				writeLines(&buf, "+", sc.NewComments)
+				fmt.Fprintf(&buf, " [%s]\n", name)
+			}
+		}
+		if !header {
+			fmt.Fprintf(&buf, "@@ [%s] @@\n", name)
+		}
+		for _, kc := range d.Keys {
+			if kc.Section != name {
+				continue
+			}
+			switch kc.Kind {
+			case ChangeAdded:
+				writeLines(&buf, "+", kc.NewComments)
+				fmt.Fprintf(&buf, "+%s%s%s\n", kc.Key, d.spliter, kc.New)
+			case ChangeRemoved:
+				writeLines(&buf, "-", kc.OldComments)
+				fmt.Fprintf(&buf, "-%s%s%s\n", kc.Key, d.spliter, kc.Old)
+			case ChangeValue:
+				writeLines(&buf, "-", kc.OldComments)
+				fmt.Fprintf(&buf, "-%s%s%s\n", kc.Key, d.spliter, kc.Old)
+				writeLines(&buf, "+", kc.NewComments)
+				fmt.Fprintf(&buf, "+%s%s%s\n", kc.Key, d.spliter, kc.New)
+			default:
+				writeLines(&buf, "-", kc.OldComments)
+				writeLines(&buf, "+", kc.NewComments)
+				fmt.Fprintf(&buf, " %s%s%s\n", kc.Key, d.spliter, kc.New)
+			}
+		}
+	}
+	return buf.String()
+}
+
+// JSON render the diff as indented JSON.
+func (d *ConfigDiff) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// writeLines write the non-blank lines with prefix.
+func writeLines(buf *bytes.Buffer, prefix string, lines []string) {
+	for _, line := range lines {
+		if line != "" {
+			fmt.Fprintf(buf, "%s%s\n", prefix, line)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package goconf
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func parseString(t *testing.T, s string) *Config {
+	c := New()
+	if err := c.ParseReader(strings.NewReader(s)); err != nil {
+		t.Fatalf("c.ParseReader() failed (%s)", err.Error())
+	}
+	return c
+}
+
+func TestDiff(t *testing.T) {
+	a := parseString(t, "[core]\nid 1\n# old\ncol goconf\ngone x\n[old]\nk v\n")
+	b := parseString(t, "[core]\nid 2\n# new\ncol goconf\nadded y\n[new]\nk v\n")
+	d := Diff(a, b)
+	if d.Empty() {
+		t.Fatal("Diff() is empty")
+	}
+	want := `--- a
++++ b
+@@ [core] @@
+-id 1
++id 2
+-# old
++# new
+ col goconf
+-gone x
++added y
+-[old]
+-k v
++[new]
++k v
+`
+	if d.String() != want {
+		t.Errorf("d.String() got\n%s\nwant\n%s", d.String(), want)
+	}
+	js, err := d.JSON()
+	if err != nil {
+		t.Fatalf("d.JSON() failed (%s)", err.Error())
+	}
+	var r struct {
+		Sections []struct{ Section, Kind string }
+		Keys     []struct{ Section, Key, Kind, Old, New string }
+	}
+	if err = json.Unmarshal(js, &r); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Sections) != 2 || r.Sections[0].Kind != "removed" || r.Sections[1].Kind != "added" {
+		t.Errorf("sections %+v not [removed added]", r.Sections)
+	}
+	if len(r.Keys) != 6 || r.Keys[0].Kind != "changed" || r.Keys[0].Old != "1" || r.Keys[0].New != "2" || r.Keys[1].Kind != "comment" {
+		t.Errorf("keys %+v unexpected", r.Keys)
+	}
+	if d = Diff(a, a); !d.Empty() || d.String() != "" {
+		t.Errorf("Diff(a, a) not empty: %s", d.String())
+	}
+}
@@ -0,0 +1,200 @@
+package goconf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// duration units
+	Day  = 24 * time.Hour
+	Week = 7 * Day
+)
+
+// timeUnits is the duration unit by lower-case name.
+var timeUnits = map[string]time.Duration{
+	"ns":      time.Nanosecond,
+	"nsec":    time.Nanosecond,
+	"us":      time.Microsecond,
+	"µs":      time.Microsecond, // U+00B5 micro sign
+	"μs":      time.Microsecond, // U+03BC greek letter mu
+	"usec":    time.Microsecond,
+	"ms":      time.Millisecond,
+	"msec":    time.Millisecond,
+	"s":       time.Second,
+	"sec":     time.Second,
+	"secs":    time.Second,
+	"second":  time.Second,
+	"seconds": time.Second,
+	"m":       time.Minute,
+	"min":     time.Minute,
+	"mins":    time.Minute,
+	"minute":  time.Minute,
+	"minutes": time.Minute,
+	"h":       time.Hour,
+	"hour":    time.Hour,
+	"hours":   time.Hour,
+	"d":       Day,
+	"day":     Day,
+	"days":    Day,
+	"w":       Week,
+	"week":    Week,
+	"weeks":   Week,
+}
+
+// durationUnit return the unit of a bare number duration, c may be nil.
+func (c *Config) durationUnit() time.Duration {
+	if c == nil || c.DurationUnit <= 0 {
+		return time.Nanosecond
+	}
+	return c.DurationUnit
+}
+
+// parseTime parse a duration such as "10s", "1h30m", "1.5d" or "2 weeks".
+// A bare number is in unit, nanoseconds if unit is 0.
+func parseTime(v string, unit time.Duration) (time.Duration, error) {
+	if unit <= 0 {
+		unit = time.Nanosecond
+	}
+	var (
+		s   = strings.TrimSpace(v)
+		neg = false
+		sum int64
+	)
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	if s == "" {
+		return 0, errors.New(fmt.Sprintf("invalid duration: %q", v))
+	}
+	for s != "" {
+		// number
+		i := 0
+		for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.' || s[i] == '_') {
+			i++
+		}
+		num := strings.ReplaceAll(s[:i], "_", "")
+		if num == "" || num == "." {
+			return 0, errors.New(fmt.Sprintf("invalid duration: %q", v))
+		}
+		s = strings.TrimLeft(s[i:], " ")
+		// unit
+		j := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) })
+		if j < 0 {
+			j = len(s)
+		}
+		name := strings.ToLower(s[:j])
+		s = strings.TrimLeft(s[j:], " ")
+		u, ok := timeUnits[name]
+		if name == "" {
+			if sum != 0 || s != "" {
+				return 0, errors.New(fmt.Sprintf("missing unit in duration: %q", v))
+			}
+			u, ok = unit, true
+		}
+		if !ok {
+			return 0, errors.New(fmt.Sprintf("unknown unit: %q in duration: %q", name, v))
+		}
+		part, err := parseTimePart(num, u)
+		if err == errTimeResolution {
+			return 0, errors.New(fmt.Sprintf("duration: %q is finer than 1ns", v))
+		}
+		if err != nil || part > math.MaxInt64-sum {
+			return 0, errors.New(fmt.Sprintf("invalid duration: %q", v))
+		}
+		sum += part
+	}
+	if neg {
+		sum = -sum
+	}
+	return time.Duration(sum), nil
+}
+
+// errTimeResolution is a duration fraction finer than a nanosecond.
+var errTimeResolution = errors.New("duration finer than 1ns")
+
+// pow10 return 10 to the power of n, n <= 19.
+func pow10(n int) uint64 {
+	p := uint64(1)
+	for ; n > 0; n-- {
+		p *= 10
+	}
+	return p
+}
+
+// parseTimePart parse one "<number><unit>" component, the number may have a
+// fraction.
+func parseTimePart(num string, u time.Duration) (int64, error) {
+	ip, fp := num, ""
+	if i := strings.IndexByte(num, '.'); i >= 0 {
+		ip, fp = num[:i], num[i+1:]
+	}
+	var (
+		n   uint64
+		err error
+	)
+	if ip != "" {
+		if n, err = strconv.ParseUint(ip, 10, 64); err != nil {
+			return 0, err
+		}
+	}
+	if n > uint64(math.MaxInt64/int64(u)) {
+		return 0, strconv.ErrRange
+	}
+	part := int64(n) * int64(u)
+	// exact fraction of the unit, digits below 1ns are an error rather than
+	// truncated, so "1.5" with the ns unit is not read as 1ns
+	if fp = strings.TrimRight(fp, "0"); fp != "" {
+		if len(fp) > 19 {
+			return 0, errTimeResolution
+		}
+		f, err := strconv.ParseUint(fp, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		hi, lo := bits.Mul64(f, uint64(u))
+		frac, rem := bits.Div64(hi, lo, pow10(len(fp)))
+		if rem != 0 {
+			return 0, errTimeResolution
+		}
+		if frac > uint64(math.MaxInt64-part) {
+			return 0, strconv.ErrRange
+		}
+		part += int64(frac)
+	}
+	return part, nil
+}
+
+// formatTime format a duration in the canonical compound form read by
+// parseTime, such as "1d2h30m" or "1s500ms".
+func formatTime(d time.Duration) string {
+	if d == 0 {
+		return "0s"
+	}
+	var (
+		b strings.Builder
+		u = uint64(d)
+	)
+	if d < 0 {
+		b.WriteByte('-')
+		u = -u
+	}
+	for _, unit := range []struct {
+		unit time.Duration
+		name string
+	}{{Day, "d"}, {time.Hour, "h"}, {time.Minute, "m"}, {time.Second, "s"}, {time.Millisecond, "ms"}, {time.Microsecond, "us"}, {time.Nanosecond, "ns"}} {
+		if n := u / uint64(unit.unit); n > 0 {
+			b.WriteString(strconv.FormatUint(n, 10))
+			b.WriteString(unit.name)
+			u -= n * uint64(unit.unit)
+		}
+	}
+	return b.String()
+}
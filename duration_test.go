@@ -0,0 +1,68 @@
+package goconf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	for _, c := range []struct {
+		v    string
+		unit time.Duration
+		d    time.Duration
+	}{
+		{"10ms", 0, 10 * time.Millisecond},
+		{"10s", 0, 10 * time.Second},
+		{"90sec", 0, 90 * time.Second},
+		{"5min", 0, 5 * time.Minute},
+		{"2hour", 0, 2 * time.Hour},
+		{"1h30m", 0, 90 * time.Minute},
+		{"1h30m0s", 0, 90 * time.Minute},
+		{"1.5s", 0, 1500 * time.Millisecond},
+		{"2d", 0, 48 * time.Hour},
+		{"1w 2d", 0, 9 * Day},
+		{"2 Days", 0, 2 * Day},
+		{"1.5µs", 0, 1500},
+		{"-1m", 0, -time.Minute},
+		{"100", 0, 100},
+		{"100", time.Second, 100 * time.Second},
+		{"1.5", time.Second, 1500 * time.Millisecond},
+		{"1.50", time.Microsecond, 1500},
+		{"0.000000001s", 0, 1},
+	} {
+		if d, err := parseTime(c.v, c.unit); err != nil || d != c.d {
+			t.Errorf("parseTime(%q) = %v, %v not equals %v", c.v, d, err, c.d)
+		}
+	}
+	for _, v := range []string{"", "s", "1x", "1h30", "10 20", "1h-1m", "300000w", ".", "1.5", "0.5ns", "1.0000000001s"} {
+		if d, err := parseTime(v, 0); err == nil {
+			t.Errorf("parseTime(%q) = %v, no error", v, d)
+		}
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	for _, d := range []time.Duration{0, 1, 1500 * time.Millisecond, 26*time.Hour + 30*time.Minute, -time.Minute, 1<<63 - 1} {
+		v := formatTime(d)
+		if pd, err := parseTime(v, 0); err != nil || pd != d {
+			t.Errorf("parseTime(formatTime(%d) = %q) = %v, %v", d, v, pd, err)
+		}
+	}
+	if v := formatTime(26*time.Hour + 30*time.Minute); v != "1d2h30m" {
+		t.Errorf("formatTime() %q not equals \"1d2h30m\"", v)
+	}
+}
+
+func TestDurationUnit(t *testing.T) {
+	c := parseString(t, "[core]\nsleep 10\n")
+	c.DurationUnit = time.Second
+	if d, err := c.Get("core").Duration("sleep"); err != nil || d != 10*time.Second {
+		t.Errorf("Duration(\"sleep\") = %v, %v not equals 10s", d, err)
+	}
+	var tc struct {
+		Sleep time.Duration `goconf:"core:sleep:time"`
+	}
+	if err := c.Unmarshal(&tc); err != nil || tc.Sleep != 10*time.Second {
+		t.Errorf("Unmarshal() Sleep = %v, %v not equals 10s", tc.Sleep, err)
+	}
+}
@@ -0,0 +1,424 @@
+package goconf
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueMarshaler is the interface implemented by types that can encode
+// themselves into a config value, the inverse of ValueUnmarshaler.
+type ValueMarshaler interface {
+	MarshalGoconf(s *Section, key string) (string, error)
+}
+
+var (
+	valueMarshalerType = reflect.TypeOf((*ValueMarshaler)(nil)).Elem()
+	textMarshalerType  = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// formatters format the values of types whose String method is not read back
+// by their parser.
+var formatters = map[reflect.Type]func(v reflect.Value) string{
+	reflect.TypeOf(time.Duration(0)): func(v reflect.Value) string {
+		return formatTime(time.Duration(v.Int()))
+	},
+	reflect.TypeOf(os.FileMode(0)): func(v reflect.Value) string {
+		return fmt.Sprintf("%#o", v.Uint())
+	},
+	timeType: func(v reflect.Value) string {
+		return v.Interface().(time.Time).Format(time.RFC3339Nano)
+	},
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// Marshal return a new Config built from the struct v, see Config.Marshal.
+func Marshal(v interface{}) (*Config, error) {
+	c := New()
+	if err := c.Marshal(v); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Marshal add the sections and keys of the struct, or pointer to struct, v
+// to the config in field order, existing keys are updated. The tags are the
+// same as Unmarshal, so that Unmarshal reads the values back:
+//
+//	// Field is written as "2m30s".
+//	Field time.Duration `goconf:"base:myName:time"`
+//
+//	// Field is written as "512MiB".
+//	Field int `goconf:"base:myName:memory"`
+//
+//	// Field is written as "a,b" and "a=1,b=2", map keys are sorted.
+//	Field []string `goconf:"base:myName:,"`
+//	Field map[string]int `goconf:"base:myName:,"`
+//
+//	// Field is not written if empty.
+//	Field int `goconf:"base:myName,omitempty"`
+//
+//	// Field is written below the comment line "# listen address".
+//	Field string `goconf:"base:myName" comment:"listen address"`
+//
+// Types implementing ValueMarshaler or encoding.TextMarshaler encode their
+// own values. Structs tagged by a section name create the section, the
+// comment tag is then written above the section. A slice element is written
+// to the section of its ":section" field, or its index if that is empty. Nil
+// pointers are not written.
+func (c *Config) Marshal(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return errors.New(fmt.Sprintf("goconf: Marshal(non-struct %s)", reflect.TypeOf(v)))
+	}
+	return c.marshalStruct(rv, "")
+}
+
+// marshalStruct encode the fields of the struct rv into section, "" is the
+// top-level struct whose fields name their section in the tag.
+func (c *Config) marshalStruct(rv reflect.Value, section string) error {
+	rt := rv.Type()
+	n := rv.NumField()
+	// enum every struct field
+	for i := 0; i < n; i++ {
+		vf := rv.Field(i)
+		tf := rt.Field(i)
+		tag := tf.Tag.Get("goconf")
+		omitEmpty := strings.HasSuffix(tag, ",omitempty")
+		tag = strings.TrimSuffix(tag, ",omitempty")
+		comment := tf.Tag.Get("comment")
+		// if tag "-" ignore
+		if tag == "-" {
+			continue
+		}
+		// flatten embedded struct
+		if tf.Anonymous && tag == "" {
+			if ft := indirectType(tf.Type); ft.Kind() == reflect.Struct && !isValueType(ft) {
+				if vf.Kind() == reflect.Ptr {
+					if vf.IsNil() {
+						continue
+					}
+					vf = vf.Elem()
+				}
+				if err := c.marshalStruct(vf, section); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		// if tag empty, section name or unexported field ignore
+		if tag == "" || tag == "omitempty" || tag == ":section" || !tf.IsExported() {
+			continue
+		}
+		if omitEmpty && isEmptyValue(vf) {
+			continue
+		}
+		if !strings.Contains(tag, ":") {
+			name := tag
+			if section != "" {
+				name = section + "." + name
+			}
+			// struct mapped to a section
+			if ft := indirectType(tf.Type); ft.Kind() == reflect.Struct && !isValueType(ft) {
+				if vf.Kind() == reflect.Ptr {
+					if vf.IsNil() {
+						continue
+					}
+					vf = vf.Elem()
+				}
+				c.addSection(name, comment)
+				if err := c.marshalStruct(vf, name); err != nil {
+					return err
+				}
+				continue
+			}
+			// slice or map of structs mapped to the prefixed sections
+			if isSectionsType(tf.Type) {
+				if err := c.marshalSections(vf, name, comment); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		name, key, format := section, "", ""
+		if section == "" {
+			tagArr := strings.SplitN(tag, ":", 3)
+			if len(tagArr) < 2 {
+				return errors.New(fmt.Sprintf("error tag: %s, must be section:field:delim(optional)", tag))
+			}
+			name, key = tagArr[0], tagArr[1]
+			if len(tagArr) == 3 {
+				format = tagArr[2]
+			}
+		} else {
+			tagArr := strings.SplitN(tag, ":", 2)
+			key = tagArr[0]
+			if len(tagArr) == 2 {
+				format = tagArr[1]
+			}
+		}
+		if (vf.Kind() == reflect.Ptr || vf.Kind() == reflect.Interface) && vf.IsNil() {
+			continue
+		}
+		s := c.Add(name)
+		value, err := c.encodeField(s, key, format, vf, tf)
+		if err != nil {
+			return err
+		}
+		if comment == "" {
+			s.Add(key, value)
+		} else {
+			s.Add(key, value, comment)
+		}
+	}
+	return nil
+}
+
+// addSection add the section, with comment if not empty.
+func (c *Config) addSection(name, comment string) *Section {
+	if comment == "" {
+		return c.Add(name)
+	}
+	return c.Add(name, comment)
+}
+
+// marshalSections encode every element of the slice or map of structs vf
+// into the section named prefix.<key>, map keys sorted, or for a slice element
+// its ":section" field, prefixed if not yet, or prefix.<index> if it is empty.
+func (c *Config) marshalSections(vf reflect.Value, prefix, comment string) error {
+	var (
+		names []string
+		elems []reflect.Value
+	)
+	if vf.Kind() == reflect.Slice {
+		for i := 0; i < vf.Len(); i++ {
+			names = append(names, strconv.Itoa(i))
+			elems = append(elems, vf.Index(i))
+		}
+	} else {
+		keys := vf.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			names = append(names, k.String())
+			elems = append(elems, vf.MapIndex(k))
+		}
+	}
+	for i, ev := range elems {
+		if ev.Kind() == reflect.Ptr {
+			if ev.IsNil() {
+				continue
+			}
+			ev = ev.Elem()
+		}
+		if name := sectionName(ev); name != "" && vf.Kind() == reflect.Slice {
+			names[i] = strings.TrimPrefix(name, prefix+".")
+		}
+		c.addSection(prefix+"."+names[i], comment)
+		if err := c.marshalStruct(ev, prefix+"."+names[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sectionName return the value of the string field of struct rv tagged
+// ":section", or "" if there is none.
+func sectionName(rv reflect.Value) string {
+	rt := rv.Type()
+	for i := 0; i < rv.NumField(); i++ {
+		vf := rv.Field(i)
+		tf := rt.Field(i)
+		tag := tf.Tag.Get("goconf")
+		if tf.Anonymous && tag == "" {
+			if vf.Kind() == reflect.Ptr {
+				if vf.IsNil() {
+					continue
+				}
+				vf = vf.Elem()
+			}
+			if vf.Kind() == reflect.Struct && !isValueType(vf.Type()) {
+				if name := sectionName(vf); name != "" {
+					return name
+				}
+			}
+			continue
+		}
+		if tag == ":section" && tf.IsExported() && vf.Kind() == reflect.String {
+			return vf.String()
+		}
+	}
+	return ""
+}
+
+// encodeField encode the struct field vf for the key of section s, format is
+// the tag option.
+func (c *Config) encodeField(s *Section, key, format string, vf reflect.Value, tf reflect.StructField) (string, error) {
+	if vf.Kind() == reflect.Ptr && !hasFormatter(vf.Type()) && format != "json" {
+		return c.encodeField(s, key, format, vf.Elem(), tf)
+	}
+	switch {
+	case format == "json":
+		b, err := json.Marshal(vf.Interface())
+		return string(b), err
+	case format == "memory" && isUint(vf.Kind()):
+		return formatMemory(vf.Uint()), nil
+	case format == "memory" && isInt(vf.Kind()):
+		if vf.Int() < 0 {
+			return "", errors.New(fmt.Sprintf("negative memory size of struct field: %s", tf.Name))
+		}
+		return formatMemory(uint64(vf.Int())), nil
+	case format == "strict" && vf.Kind() == reflect.Bool:
+		return strconv.FormatBool(vf.Bool()), nil
+	case format == "infer" && vf.Kind() == reflect.Interface:
+		return c.encodeValue(s, key, vf, nil)
+	case format != "" && vf.Type() == timeType:
+		return vf.Interface().(time.Time).Format(timeLayout(format)), nil
+	case format == "time" && vf.Kind() == reflect.Int64:
+		return formatTime(time.Duration(vf.Int())), nil
+	case format != "" && vf.Kind() == reflect.Int64:
+		return "", errors.New(fmt.Sprintf("unknown tag: %s in struct field: %s (support tags: \"memory\", \"time\")", format, tf.Name))
+	case format != "" && (isInt(vf.Kind()) || isUint(vf.Kind())):
+		return "", errors.New(fmt.Sprintf("unknown tag: %s in struct field: %s (support tags: \"memory\")", format, tf.Name))
+	default:
+		return c.encodeValue(s, key, vf, listLevels(format))
+	}
+}
+
+// encodeValue encode v for the key of section s in the form read by
+// decodeValue. Lists and maps are joined by the first level of levels, their
+// elements encoded with the rest.
+func (c *Config) encodeValue(s *Section, key string, v reflect.Value, levels []string) (string, error) {
+	t := v.Type()
+	if f, ok := formatters[t]; ok {
+		return f(v), nil
+	}
+	if hasFormatter(t) {
+		if t.Kind() == reflect.Ptr && v.IsNil() {
+			return "", nil
+		}
+		return v.Interface().(fmt.Stringer).String(), nil
+	}
+	if m, ok := marshaler(v); ok {
+		switch m := m.(type) {
+		case ValueMarshaler:
+			return m.MarshalGoconf(s, key)
+		case encoding.TextMarshaler:
+			b, err := m.MarshalText()
+			return string(b), err
+		}
+	}
+	format, rest := "", []string(nil)
+	if len(levels) > 0 {
+		format, rest = levels[0], levels[1:]
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, t.Bits()), nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return "", nil
+		}
+		return c.encodeValue(s, key, v.Elem(), levels)
+	case reflect.Slice, reflect.Array:
+		if format == "" {
+			format = Delim
+		}
+		elems := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			e, err := c.encodeValue(s, key, v.Index(i), rest)
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, e)
+		}
+		return joinList(elems, format)
+	case reflect.Map:
+		delim, sep := mapFormat(format)
+		pairs := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			ks, err := c.encodeValue(s, key, k, nil)
+			if err != nil {
+				return "", err
+			}
+			vs, err := c.encodeValue(s, key, v.MapIndex(k), rest)
+			if err != nil {
+				return "", err
+			}
+			if strings.Contains(ks, sep) || ks != strings.TrimSpace(ks) {
+				return "", errors.New(fmt.Sprintf("map key: %q contains %q or leading or trailing space", ks, sep))
+			}
+			pairs = append(pairs, ks+sep+vs)
+		}
+		sort.Strings(pairs)
+		return joinList(pairs, delim)
+	}
+	return "", errors.New(fmt.Sprintf("cannot marshal unsuported kind: %s of type: %s", v.Kind(), t))
+}
+
+// marshaler return v, or its address, if it implements ValueMarshaler or
+// encoding.TextMarshaler.
+func marshaler(v reflect.Value) (interface{}, bool) {
+	t := v.Type()
+	if t.Implements(valueMarshalerType) || t.Implements(textMarshalerType) {
+		if t.Kind() == reflect.Ptr && v.IsNil() {
+			return nil, false
+		}
+		return v.Interface(), true
+	}
+	if pt := reflect.PtrTo(t); v.CanAddr() && (pt.Implements(valueMarshalerType) || pt.Implements(textMarshalerType)) {
+		return v.Addr().Interface(), true
+	}
+	return nil, false
+}
+
+// hasFormatter return true if values of t are formatted as a whole rather
+// than by kind.
+func hasFormatter(t reflect.Type) bool {
+	if _, ok := formatters[t]; ok {
+		return true
+	}
+	return lookupParser(t) != nil && t.Implements(stringerType)
+}
+
+// isEmptyValue return true if v is the zero value of its kind, as the
+// "omitempty" option of encoding/json.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			return t.IsZero()
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package goconf
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testMarshal struct {
+	ID      int                      `goconf:"core:id" comment:"server id"`
+	Name    string                   `goconf:"core:name,omitempty"`
+	Arr     []string                 `goconf:"core:arr:,"`
+	M       map[string]int           `goconf:"core:m:,"`
+	Timeout time.Duration            `goconf:"core:timeout:time"`
+	Buf     int64                    `goconf:"core:buf:memory"`
+	Mode    os.FileMode              `goconf:"core:mode"`
+	Start   time.Time                `goconf:"core:start:DateOnly"`
+	JSON    map[string][]int         `goconf:"core:json:json"`
+	Ignore  int                      `goconf:"-"`
+	Redis   *testRedis               `goconf:"redis" comment:"redis pool"`
+	Pools   map[string]*testUpstream `goconf:"pool"`
+}
+
+func TestMarshal(t *testing.T) {
+	in := testMarshal{
+		ID:      1,
+		Arr:     []string{"a", "b,c"},
+		M:       map[string]int{"b": 2, "a": 1},
+		Timeout: 90 * time.Second,
+		Buf:     512 * MiB,
+		Mode:    0644,
+		Start:   time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		JSON:    map[string][]int{"a": {1}},
+		Ignore:  1,
+		Redis:   &testRedis{Addr: "127.0.0.1:6379", Pool: &testPool{Size: 8}},
+		Pools:   map[string]*testUpstream{"b": {Addr: "10.0.0.2"}, "a": {Addr: "10.0.0.1"}},
+	}
+	c, err := Marshal(&in)
+	if err != nil {
+		t.Fatalf("Marshal() failed (%s)", err.Error())
+	}
+	var buf bytes.Buffer
+	if err = c.write(&buf, false); err != nil {
+		t.Fatalf("c.write() failed (%s)", err.Error())
+	}
+	want := `[core]
+#server id
+id 1
+arr a,"b,c"
+m a=1,b=2
+timeout 1m30s
+buf 512MiB
+mode 0644
+start 2024-01-02
+json {"a":[1]}
+#redis pool
+[redis]
+addr 127.0.0.1:6379
+timeout 0s
+[redis.pool]
+size 8
+[pool.a]
+addr 10.0.0.1
+weight 0
+[pool.b]
+addr 10.0.0.2
+weight 0
+`
+	if buf.String() != want {
+		t.Errorf("Marshal() =\n%s\nwant\n%s", buf.String(), want)
+	}
+	c = parseString(t, buf.String())
+	var out testMarshal
+	if err = c.Unmarshal(&out); err != nil {
+		t.Fatalf("c.Unmarshal() failed (%s)", err.Error())
+	}
+	if out.ID != in.ID || out.Arr[1] != "b,c" || out.M["b"] != 2 || out.Timeout != in.Timeout || out.Buf != in.Buf ||
+		out.Mode != in.Mode || !out.Start.Equal(in.Start) || out.JSON["a"][0] != 1 || out.Redis.Pool.Size != 8 ||
+		out.Pools["b"].Addr != "10.0.0.2" {
+		t.Errorf("c.Unmarshal() = %+v", out)
+	}
+	var q struct {
+		L []string `goconf:"core:l"`
+	}
+	q.L = []string{`x"y`}
+	if c, err = Marshal(&q); err != nil {
+		t.Fatalf("Marshal() failed (%s)", err.Error())
+	}
+	q.L = nil
+	if err = c.Unmarshal(&q); err != nil || len(q.L) != 1 || q.L[0] != `x"y` {
+		t.Errorf("c.Unmarshal() = %q, %v", q.L, err)
+	}
+	// slice sections are named by their ":section" field, else their index
+	var ups struct {
+		Pools []testUpstream `goconf:"pool"`
+	}
+	ups.Pools = []testUpstream{{Name: "pool.a", Addr: "10.0.0.1"}, {Name: "b", Addr: "10.0.0.2"}, {Addr: "10.0.0.3"}}
+	if c, err = Marshal(&ups); err != nil {
+		t.Fatalf("Marshal() failed (%s)", err.Error())
+	}
+	if names := c.Sections(); strings.Join(names, " ") != "pool.a pool.b pool.2" {
+		t.Errorf("Marshal() sections = %v", names)
+	}
+	ups.Pools = nil
+	if err = c.Unmarshal(&ups); err != nil || len(ups.Pools) != 3 || ups.Pools[1].Name != "pool.b" || ups.Pools[1].Addr != "10.0.0.2" {
+		t.Errorf("c.Unmarshal() = %+v, %v", ups.Pools, err)
+	}
+	var neg struct {
+		Buf int64 `goconf:"core:buf:memory"`
+	}
+	neg.Buf = -KiB
+	if _, err = Marshal(&neg); err == nil || !strings.Contains(err.Error(), "negative memory size") {
+		t.Errorf("Marshal() negative memory size error = %v", err)
+	}
+	if _, err = Marshal(1); err == nil || !strings.Contains(err.Error(), "non-struct") {
+		t.Errorf("Marshal(1) error = %v", err)
+	}
+}
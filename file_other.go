@@ -0,0 +1,17 @@
+//go:build !unix
+
+package goconf
+
+import (
+	"os"
+)
+
+// chown is a no-op, file ownership is not portable.
+func chown(f *os.File, fi os.FileInfo) error {
+	return nil
+}
+
+// syncDir is a no-op, directories cannot be synced on this platform.
+func syncDir(dir string) error {
+	return nil
+}
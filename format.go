@@ -0,0 +1,121 @@
+package goconf
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// FormatOptions control the canonical form written by Format.
+type FormatOptions struct {
+	// Comment is the comment marker, "" means Comment.
+	Comment string
+	// Spliter is the key-value separator, "" means Spliter.
+	Spliter string
+	// Align pads the keys so the values of a section line up.
+	Align bool
+	// SortKeys sorts the keys of every section, comments move with their key.
+	SortKeys bool
+}
+
+// Format read a config from r and write it to w in canonical form:
+//   - every key-value uses the separator, optionally aligned,
+//   - comments are written as the marker, one space and the text,
+//   - runs of blank lines are collapsed and sections are separated by
+//     exactly one blank line.
+func Format(r io.Reader, w io.Writer, opts FormatOptions) error {
+	c, err := formatConfig(r, opts)
+	if err != nil {
+		return err
+	}
+	return c.write(w, opts.Align)
+}
+
+// FormatFile format the config file in place, like Format, and report
+// whether it changed. The file is rewritten atomically under the same lock
+// as Config.Save, and only if its formatting differs, so it also returns
+// ErrLockUnsupported where Save does.
+func FormatFile(file string, opts FormatOptions) (bool, error) {
+	l, err := lockFile(file, 0)
+	if err != nil {
+		return false, err
+	}
+	defer l.Unlock()
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	c, err := formatConfig(bytes.NewReader(src), opts)
+	if err != nil {
+		return false, err
+	}
+	var out bytes.Buffer
+	if err = c.write(&out, opts.Align); err != nil {
+		return false, err
+	}
+	if bytes.Equal(src, out.Bytes()) {
+		return false, nil
+	}
+	return true, c.saveFile(file, opts.Align)
+}
+
+// formatConfig read a config from r in the canonical form of opts.
+func formatConfig(r io.Reader, opts FormatOptions) (*Config, error) {
+	c := New()
+	if opts.Comment != "" {
+		c.Comment = opts.Comment
+	}
+	if opts.Spliter != "" {
+		c.Spliter = opts.Spliter
+	}
+	if err := c.ParseReader(r); err != nil {
+		return nil, err
+	}
+	for i, name := range c.dataOrder {
+		s := c.data[name]
+		s.comments = formatComments(c.Comment, s.comments, i > 0)
+		if opts.SortKeys {
+			sort.Strings(s.dataOrder)
+		}
+		for j, k := range s.dataOrder {
+			comments := s.dataComments[k]
+			s.dataComments[k] = formatComments(c.Comment, comments, j > 0 && len(comments) > 0 && comments[0] == "")
+		}
+	}
+	c.comments = formatComments(c.Comment, c.comments, len(c.comments) > 0 && c.comments[0] == "")
+	if len(c.comments) == 1 && c.comments[0] == "" {
+		// a lone blank line at the end of the file
+		c.comments = nil
+	}
+	return c, nil
+}
+
+// formatComments normalise the comment lines above a section or key, if
+// blank is true a single blank line is kept in front of them.
+func formatComments(marker string, lines []string, blank bool) []string {
+	comments := []string{}
+	if blank {
+		comments = append(comments, "")
+	}
+	for _, line := range lines {
+		if line == "" {
+			if len(comments) > 0 && comments[len(comments)-1] != "" {
+				comments = append(comments, "")
+			}
+			continue
+		}
+		text := strings.TrimSpace(strings.TrimLeft(line, marker))
+		if text == "" {
+			comments = append(comments, marker)
+		} else {
+			comments = append(comments, marker+" "+text)
+		}
+	}
+	// no blank line between a comment and what it describes
+	for len(comments) > 0 && comments[len(comments)-1] == "" && (len(comments) > 1 || !blank) {
+		comments = comments[:len(comments)-1]
+	}
+	return comments
+}
@@ -0,0 +1,98 @@
+package goconf
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	src := `#top
+#
+
+[core]
+#id
+id 1
+
+
+col   goconf
+# another
+ long_key  v
+
+a 2
+##
+
+[test]
+b yes
+# end
+`
+	want := `# top
+#
+[core]
+# id
+id       1
+
+col      goconf
+# another
+long_key v
+
+a        2
+
+#
+[test]
+b yes
+# end
+`
+	var out bytes.Buffer
+	if err := Format(strings.NewReader(src), &out, FormatOptions{Align: true}); err != nil {
+		t.Fatalf("Format() failed (%s)", err.Error())
+	}
+	if out.String() != want {
+		t.Errorf("Format() got\n%s\nwant\n%s", out.String(), want)
+	}
+	// formatting is idempotent
+	var again bytes.Buffer
+	if err := Format(strings.NewReader(want), &again, FormatOptions{Align: true}); err != nil {
+		t.Fatalf("Format() failed (%s)", err.Error())
+	}
+	if again.String() != want {
+		t.Errorf("Format() not idempotent, got\n%s", again.String())
+	}
+}
+
+func TestFormatSortKeys(t *testing.T) {
+	var out bytes.Buffer
+	src := "[core]\nb=2\n# a\na = 1\n"
+	if err := Format(strings.NewReader(src), &out, FormatOptions{Spliter: "=", Align: true, SortKeys: true}); err != nil {
+		t.Fatalf("Format() failed (%s)", err.Error())
+	}
+	if want := "[core]\n# a\na = 1\nb = 2\n"; out.String() != want {
+		t.Errorf("Format() got %q want %q", out.String(), want)
+	}
+}
+
+func TestFormatFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "fmt.conf")
+	if err := os.WriteFile(file, []byte("[core]\nid   1\nlong_key v\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	opts := FormatOptions{Align: true}
+	if changed, err := FormatFile(file, opts); err != nil || !changed {
+		t.Fatalf("FormatFile() = %v, %v", changed, err)
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[core]\nid       1\nlong_key v\n"; string(b) != want {
+		t.Errorf("FormatFile() wrote %q, want %q", b, want)
+	}
+	if fi, err := os.Stat(file); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("file mode %v, %v not equals 0600", fi.Mode().Perm(), err)
+	}
+	if changed, err := FormatFile(file, opts); err != nil || changed {
+		t.Errorf("FormatFile() formatted file = %v, %v", changed, err)
+	}
+}
@@ -0,0 +1,159 @@
+package goconf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// list
+	quote  = '"'
+	escape = '\\'
+)
+
+// keepListSpace return true if the spaces around unquoted list elements are
+// kept, c may be nil.
+func (c *Config) keepListSpace() bool {
+	return c != nil && c.KeepListSpace
+}
+
+// splitList split a list value by delim.
+//
+// An element may be double-quoted to contain delim or leading and trailing
+// spaces, inside quotes \" and \\ are escapes. Outside quotes a backslash
+// escapes delim. Unquoted elements are trimmed unless keepSpace is true. An
+// empty value is an empty list.
+//
+// A quoted part that is not a whole element, such as in `"a,b",c`, is kept
+// as is with its quotes but still not split, so nested lists can be split
+// again by their own delimiter. A quote that does not start an element, or
+// is never closed, is a literal character, such as in `a"b`.
+func splitList(v, delim string, keepSpace bool) ([]string, error) {
+	var (
+		elems  = []string{}
+		b      strings.Builder
+		quoted bool // the element is a quoted string
+	)
+	if delim == "" {
+		return nil, errors.New("empty delimiter")
+	}
+	if strings.TrimSpace(v) == "" {
+		return elems, nil
+	}
+	flush := func() {
+		e := b.String()
+		if !quoted && !keepSpace {
+			e = strings.TrimSpace(e)
+		}
+		elems = append(elems, e)
+		b.Reset()
+		quoted = false
+	}
+	for i := 0; i < len(v); {
+		c := v[i]
+		switch {
+		case strings.HasPrefix(v[i:], delim):
+			flush()
+			i += len(delim)
+		case c == escape && strings.HasPrefix(v[i+1:], delim):
+			b.WriteString(delim)
+			i += 1 + len(delim)
+		case c == quote && !quoted && strings.TrimSpace(b.String()) == "":
+			text, j, ok := unquote(v, i)
+			if !ok {
+				// never closed, a literal quote
+				b.WriteByte(c)
+				i++
+				continue
+			}
+			rest := v[j:]
+			if k := strings.Index(rest, delim); k >= 0 {
+				rest = rest[:k]
+			}
+			if strings.TrimSpace(rest) == "" {
+				// the whole element
+				b.Reset()
+				b.WriteString(text)
+				quoted = true
+				i = j + len(rest)
+			} else {
+				b.WriteString(v[i:j])
+				i = j
+			}
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+	return elems, nil
+}
+
+// unquote read the quoted string starting at v[i], it return the unescaped
+// text and the index after the closing quote.
+func unquote(v string, i int) (string, int, bool) {
+	var b strings.Builder
+	for i++; i < len(v); i++ {
+		switch {
+		case v[i] == escape && i+1 < len(v) && (v[i+1] == quote || v[i+1] == escape):
+			i++
+			b.WriteByte(v[i])
+		case v[i] == quote:
+			return b.String(), i + 1, true
+		default:
+			b.WriteByte(v[i])
+		}
+	}
+	return "", 0, false
+}
+
+// joinList join elements by delim so that splitList returns them unchanged,
+// quoting elements where needed.
+func joinList(elems []string, delim string) (string, error) {
+	if delim == "" {
+		return "", errors.New("empty delimiter")
+	}
+	quoted := make([]string, len(elems))
+	for i, e := range elems {
+		if strings.ContainsRune(e, CRLF) {
+			return "", errors.New(fmt.Sprintf("list element: %q contains newline", e))
+		}
+		if e == "" && len(elems) == 1 || e != strings.TrimSpace(e) || strings.Contains(e, delim) ||
+			strings.ContainsRune(e, escape) || strings.ContainsRune(e, quote) {
+			e = strings.ReplaceAll(e, string(escape), string(escape)+string(escape))
+			e = strings.ReplaceAll(e, string(quote), string(escape)+string(quote))
+			e = string(quote) + e + string(quote)
+		}
+		quoted[i] = e
+	}
+	return strings.Join(quoted, delim), nil
+}
+
+// splitPair split a map element "k=v" by sep.
+func splitPair(e, sep string, keepSpace bool) (string, string, error) {
+	kv := strings.SplitN(e, sep, 2)
+	if len(kv) < 2 {
+		return "", "", errors.New(fmt.Sprintf("error map: %s, must be split by \"%s\"", e, sep))
+	}
+	if !keepSpace {
+		kv[0], kv[1] = strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+	}
+	return kv[0], kv[1], nil
+}
+
+// mapFormat return the element delimiter and key-value separator of a map
+// tag option "delim" or "delim:sep", "," means "," and "=", ";::" means ";"
+// and ":". A ':' at the start is part of the delimiter, so ";:" is the
+// delimiter ";:" with "=".
+func mapFormat(format string) (string, string) {
+	delim, sep := Delim, "="
+	if format == "" {
+		return delim, sep
+	}
+	delim = format
+	if i := strings.IndexByte(format[1:], ':'); i >= 0 && i+2 < len(format) {
+		delim, sep = format[:i+1], format[i+2:]
+	}
+	return delim, sep
+}
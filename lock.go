@@ -0,0 +1,124 @@
+package goconf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// lock
+	LockExt            = ".lock"
+	DefaultLockTimeout = 10 * time.Second
+	lockRetry          = 10 * time.Millisecond
+)
+
+// ErrLockUnsupported is returned by Save and Edit on platforms without
+// advisory file locking.
+var ErrLockUnsupported = errors.New("goconf: file locking unsupported on this platform")
+
+// A LockTimeoutError describes a config file lock that could not be taken
+// within the timeout.
+type LockTimeoutError struct {
+	File    string
+	Timeout time.Duration
+}
+
+func (e *LockTimeoutError) Error() string {
+	return fmt.Sprintf("goconf: lock %s not acquired within %s", e.File, e.Timeout)
+}
+
+// fileLock is a cross-process advisory lock on a config file.
+//
+// The lock is taken on a sidecar "file.lock" rather than the file itself,
+// because Save replaces the file by rename.
+type fileLock struct {
+	f *os.File
+}
+
+// lockFile lock the specified config file, waiting at most timeout.
+func lockFile(file string, timeout time.Duration) (*fileLock, error) {
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+	// lock the target of a symlink, as saveFile replaces it
+	file, err := resolveFile(file)
+	if err != nil {
+		return nil, err
+	}
+	name := file + LockExt
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if ok {
+			return &fileLock{f: f}, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, &LockTimeoutError{File: name, Timeout: timeout}
+		}
+		time.Sleep(lockRetry)
+	}
+}
+
+// Unlock release the lock.
+func (l *fileLock) Unlock() error {
+	err := unlock(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Edit lock the specified config file, parse it again, apply fn to the
+// fresh configuration and save it atomically while still holding the lock.
+// If file is "" the original file is used. A missing file is edited as an
+// empty configuration.
+//
+// On success c is replaced by the edited configuration, so concurrent
+// changes made by other processes are never overwritten.
+//
+// The lock is taken on the sidecar file "file.lock", which is left in place
+// like by Save. fn must not call Save or Edit on the same file, the lock is
+// not reentrant and the call blocks until LockTimeout expires. Edit returns
+// ErrLockUnsupported on platforms without advisory file locking, such as
+// Windows.
+func (c *Config) Edit(file string, fn func(*Config) error) error {
+	if file == "" {
+		file = c.file
+	}
+	if file == "" {
+		return errors.New("no file to edit config in")
+	}
+	l, err := lockFile(file, c.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+	nc := c.clone()
+	nc.file = file
+	if err = nc.Parse(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err = fn(nc); err != nil {
+		return err
+	}
+	if err = nc.saveFile(file, false); err != nil {
+		return err
+	}
+	*c = *nc
+	// the sections belong to c now
+	for _, s := range c.data {
+		s.conf = c
+	}
+	return nil
+}
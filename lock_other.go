@@ -0,0 +1,18 @@
+//go:build !unix
+
+package goconf
+
+import (
+	"os"
+)
+
+// tryLock fail with ErrLockUnsupported, advisory locking is not supported on
+// this platform.
+func tryLock(f *os.File) (bool, error) {
+	return false, ErrLockUnsupported
+}
+
+// unlock is a no-op.
+func unlock(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,69 @@
+package goconf
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEdit(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "edit.conf")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := New()
+			err := c.Edit(file, func(nc *Config) error {
+				s := nc.Add("core")
+				n, _ := s.Int("n")
+				s.Add("n", strconv.FormatInt(n+1, 10))
+				return nil
+			})
+			if err != nil {
+				t.Errorf("c.Edit(\"%s\") failed (%s)", file, err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+	c := New()
+	if err := c.Parse(file); err != nil {
+		t.Fatalf("c.Parse(\"%s\") failed (%s)", file, err.Error())
+	}
+	if n, _ := c.Get("core").Int("n"); n != 10 {
+		t.Errorf("n %d not equals 10", n)
+	}
+	// options set after Edit apply to the edited sections
+	if err := c.Edit(file, func(nc *Config) error {
+		nc.Get("core").Add("d", "10")
+		return nil
+	}); err != nil {
+		t.Fatalf("c.Edit(\"%s\") failed (%s)", file, err.Error())
+	}
+	c.DurationUnit = time.Second
+	if d, err := c.Get("core").Duration("d"); err != nil || d != 10*time.Second {
+		t.Errorf("Duration(\"d\") = %v, %v not equals 10s", d, err)
+	}
+}
+
+func TestEditLockTimeout(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "edit.conf")
+	l, err := lockFile(file, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Unlock()
+	c := New()
+	c.LockTimeout = 50 * time.Millisecond
+	err = c.Edit(file, func(*Config) error { return nil })
+	var lerr *LockTimeoutError
+	if !errors.As(err, &lerr) {
+		t.Fatalf("c.Edit() error %v not a *LockTimeoutError", err)
+	}
+	if err = c.Save(file); !errors.As(err, &lerr) {
+		t.Errorf("c.Save() error %v not a *LockTimeoutError", err)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package goconf
+
+import (
+	"os"
+	"syscall"
+)
+
+// tryLock try to take an exclusive advisory lock on f without blocking.
+func tryLock(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// unlock release the advisory lock on f.
+func unlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
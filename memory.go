@@ -0,0 +1,119 @@
+package goconf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// memoryUnit is a memory unit, si is true for the powers of 1000.
+type memoryUnit struct {
+	size uint64
+	si   bool
+}
+
+// memoryUnits is the memory unit by lower-case name.
+var memoryUnits = map[string]memoryUnit{
+	"":    {1, false},
+	"b":   {1, false},
+	"k":   {1e3, true},
+	"kb":  {1e3, true},
+	"m":   {1e6, true},
+	"mb":  {1e6, true},
+	"g":   {1e9, true},
+	"gb":  {1e9, true},
+	"t":   {1e12, true},
+	"tb":  {1e12, true},
+	"p":   {1e15, true},
+	"pb":  {1e15, true},
+	"ki":  {KiB, false},
+	"kib": {KiB, false},
+	"mi":  {MiB, false},
+	"mib": {MiB, false},
+	"gi":  {GiB, false},
+	"gib": {GiB, false},
+	"ti":  {TiB, false},
+	"tib": {TiB, false},
+	"pi":  {PiB, false},
+	"pib": {PiB, false},
+}
+
+// legacyMemSize return true if the SI memory units are powers of 1024, c may
+// be nil.
+func (c *Config) legacyMemSize() bool {
+	return c != nil && c.LegacyMemSize
+}
+
+// parseMemory parse a memory size such as "1gb", "1.5GB" or "512 MiB", if
+// legacy is true the SI units are powers of 1024.
+func parseMemory(v string, legacy bool) (uint64, error) {
+	s := strings.TrimSpace(v)
+	i := strings.IndexFunc(s, func(r rune) bool { return unicode.IsLetter(r) })
+	if i < 0 {
+		i = len(s)
+	}
+	num, name := strings.TrimSpace(s[:i]), strings.ToLower(s[i:])
+	u, ok := memoryUnits[name]
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("unknown unit: %q in memory size: %q", name, v))
+	}
+	if legacy && u.si {
+		// 1000^n -> 1024^n
+		n := 0
+		for size := u.size; size > 1; size /= 1000 {
+			n++
+		}
+		u.size = 1 << (10 * n)
+	}
+	ip, fp := num, ""
+	if j := strings.IndexByte(num, '.'); j >= 0 {
+		ip, fp = num[:j], num[j+1:]
+	}
+	if ip == "" && fp == "" {
+		return 0, errors.New(fmt.Sprintf("invalid memory size: %q", v))
+	}
+	var (
+		n   uint64
+		err error
+	)
+	if ip != "" {
+		if n, err = strconv.ParseUint(ip, 10, 64); err != nil {
+			return 0, errors.New(fmt.Sprintf("invalid memory size: %q", v))
+		}
+	}
+	hi, b := bits.Mul64(n, u.size)
+	if hi != 0 {
+		return 0, errors.New(fmt.Sprintf("memory size: %q overflows uint64", v))
+	}
+	if fp != "" {
+		f, err := strconv.ParseUint(fp, 10, 64)
+		if err != nil {
+			return 0, errors.New(fmt.Sprintf("invalid memory size: %q", v))
+		}
+		frac := uint64(float64(f) / math.Pow10(len(fp)) * float64(u.size))
+		if b+frac < b {
+			return 0, errors.New(fmt.Sprintf("memory size: %q overflows uint64", v))
+		}
+		b += frac
+	}
+	return b, nil
+}
+
+// formatMemory format a byte number with the largest IEC unit that
+// represents it exactly, the result is read back the same with or without
+// LegacyMemSize.
+func formatMemory(b uint64) string {
+	for _, u := range []struct {
+		unit uint64
+		name string
+	}{{PiB, "PiB"}, {TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"}} {
+		if b != 0 && b%u.unit == 0 {
+			return strconv.FormatUint(b/u.unit, 10) + u.name
+		}
+	}
+	return strconv.FormatUint(b, 10)
+}
@@ -0,0 +1,92 @@
+package goconf
+
+// MergeStrategy is how Merge combines a section of a layer with the same
+// section inherited from the layers before it.
+type MergeStrategy int
+
+const (
+	// MergeOverride override the inherited keys key by key.
+	MergeOverride MergeStrategy = iota
+	// MergeAppend append the values to the inherited values as list
+	// elements.
+	MergeAppend
+	// MergeReplace replace the whole inherited section.
+	MergeReplace
+)
+
+// Merger merges layered configs, see Merge.
+type Merger struct {
+	// Strategies is the merge strategy by section name, the default is
+	// MergeOverride.
+	Strategies map[string]MergeStrategy
+	// Delim is the list delimiter used to append values, "" means the Delim
+	// option of the first layer.
+	Delim string
+}
+
+// Merge merge the layers with the default Merger, see Merger.Merge.
+func Merge(layers ...*Config) *Config {
+	return (&Merger{}).Merge(layers...)
+}
+
+// Merge return a new Config built from the layers in order, later layers
+// take precedence over earlier ones.
+//
+// Besides the section strategies, a layer can delete an inherited key with a
+// "!key" line and append to an inherited value with "key += value". The
+// result holds plain keys only. Nil layers are skipped.
+func (m *Merger) Merge(layers ...*Config) *Config {
+	var (
+		c     *Config
+		delim = m.Delim
+	)
+	for _, l := range layers {
+		if l == nil {
+			continue
+		}
+		if c == nil {
+			c = l.clone()
+			c.file = ""
+			if delim == "" {
+				delim = c.listDelim()
+			}
+		}
+		for _, name := range l.dataOrder {
+			ls := l.data[name]
+			strategy := m.Strategies[name]
+			rs, ok := c.data[name]
+			if !ok {
+				rs = c.newSection(name, nil)
+				c.data[name] = rs
+				c.dataOrder = append(c.dataOrder, name)
+			} else if strategy == MergeReplace {
+				*rs = *c.newSection(name, nil)
+			}
+			if len(ls.comments) > 0 {
+				rs.comments = append([]string{}, ls.comments...)
+			}
+			for _, k := range ls.dataOrder {
+				op := ls.ops[k]
+				if op == opDelete {
+					rs.Remove(k)
+					continue
+				}
+				v := ls.data[k]
+				if old, ok := rs.data[k]; ok && old != "" && (op == opAppend || strategy == MergeAppend) {
+					v = old + delim + v
+				}
+				if _, ok := rs.data[k]; !ok {
+					rs.dataOrder = append(rs.dataOrder, k)
+				}
+				rs.data[k] = v
+				if comments := ls.dataComments[k]; len(comments) > 0 {
+					rs.dataComments[k] = append([]string{}, comments...)
+				}
+			}
+		}
+	}
+	if c == nil {
+		c = New()
+	}
+	return c
+}
@@ -0,0 +1,99 @@
+package goconf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	defaults := parseString(t, "[core]\nid 1\nhosts a,b\ndebug no\n[redis]\naddr localhost:6379\ntimeout 1s\n")
+	region := parseString(t, "[core]\nhosts += c\n!debug\n[redis]\naddr redis.region:6379\n")
+	host := parseString(t, "[core]\nid 2\n[redis]\npool 10\n[host]\nname h1\n")
+	c := Merge(defaults, nil, region, host)
+	for _, kv := range [][3]string{
+		{"core", "id", "2"},
+		{"core", "hosts", "a,b,c"},
+		{"redis", "addr", "redis.region:6379"},
+		{"redis", "timeout", "1s"},
+		{"redis", "pool", "10"},
+		{"host", "name", "h1"},
+	} {
+		if v, err := c.Get(kv[0]).String(kv[1]); err != nil || v != kv[2] {
+			t.Errorf("[%s] %s = %q (%v) not equals %q", kv[0], kv[1], v, err, kv[2])
+		}
+	}
+	if _, err := c.Get("core").String("debug"); err == nil {
+		t.Errorf("[core] debug not deleted")
+	}
+	m := &Merger{Strategies: map[string]MergeStrategy{"core": MergeAppend, "redis": MergeReplace}}
+	c = m.Merge(defaults, host)
+	if v, _ := c.Get("core").String("id"); v != "1,2" {
+		t.Errorf("[core] id %q not equals \"1,2\"", v)
+	}
+	if keys := c.Get("redis").Keys(); len(keys) != 1 || keys[0] != "pool" {
+		t.Errorf("[redis] keys %v not equals [pool]", keys)
+	}
+	// layer syntax survives a save
+	var buf bytes.Buffer
+	if err := region.write(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[core]\nhosts += c\n!debug\n[redis]\naddr redis.region:6379\n"; buf.String() != want {
+		t.Errorf("region.write() got %q want %q", buf.String(), want)
+	}
+}
+
+func TestParseLayerSyntax(t *testing.T) {
+	c := parseString(t, "[core]\nc++ 11\nhosts a\nhosts += b\nlist+= x\n")
+	s := c.Get("core")
+	if v, err := s.String("c++"); err != nil || v != "11" {
+		t.Errorf("c++ = %q, %v not equals \"11\"", v, err)
+	}
+	if v, _ := s.String("hosts"); v != "a,b" {
+		t.Errorf("hosts = %q not equals \"a,b\"", v)
+	}
+	if s.ops["list"] != opAppend {
+		t.Errorf("list+= not an append")
+	}
+	c = New()
+	c.Delim = ";"
+	if err := c.ParseReader(strings.NewReader("[core]\nhosts a\nhosts += b\n")); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := c.Get("core").String("hosts"); v != "a;b" {
+		t.Errorf("hosts = %q not equals \"a;b\"", v)
+	}
+	if v, _ := Merge(c, parseString(t, "[core]\nhosts += c\n")).Get("core").String("hosts"); v != "a;b;c" {
+		t.Errorf("merged hosts = %q not equals \"a;b;c\"", v)
+	}
+	for _, src := range []string{"[core]\n!x y\n", "[core]\n!\n"} {
+		if err := New().ParseReader(strings.NewReader(src)); err == nil {
+			t.Errorf("ParseReader(%q) no error", src)
+		}
+	}
+	// "=" spliter
+	c = New()
+	c.Spliter = "="
+	if err := c.ParseReader(strings.NewReader("[core]\nc++ = 11\nhosts += b\n")); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := c.Get("core").String("c++"); v != "11" || c.Get("core").ops["hosts"] != opAppend {
+		t.Errorf("c++ = %q, hosts op %d", v, c.Get("core").ops["hosts"])
+	}
+	// other spliters take "+=" after the spliter
+	for _, spliter := range []string{"=", ":", " "} {
+		c.Spliter = spliter
+		for _, align := range []bool{false, true} {
+			var buf bytes.Buffer
+			if err := c.write(&buf, align); err != nil {
+				t.Fatal(err)
+			}
+			nc := New()
+			nc.Spliter = spliter
+			if err := nc.ParseReader(&buf); err != nil || nc.Get("core").ops["hosts"] != opAppend || !nc.Get("core").Has("hosts") {
+				t.Errorf("append not read back (spliter %q, align %v): %v", spliter, align, err)
+			}
+		}
+	}
+}
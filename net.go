@@ -0,0 +1,133 @@
+package goconf
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+)
+
+func init() {
+	RegisterParser(parseIP)
+	RegisterParser(netip.ParseAddr)
+	RegisterParser(netip.ParsePrefix)
+	RegisterParser(parseIPNet)
+	RegisterParser(parseURL)
+	RegisterParser(parseTCPAddr)
+}
+
+// IP get config net.IP value, such as "10.0.0.1" or "::1".
+func (s *Section) IP(key string) (net.IP, error) {
+	if v, ok := s.Lookup(key); ok {
+		ip, err := parseIP(v)
+		return ip, s.valueError(key, v, err)
+	} else {
+		return nil, s.noKey(key)
+	}
+}
+
+// Addr get config netip.Addr value.
+func (s *Section) Addr(key string) (netip.Addr, error) {
+	if v, ok := s.Lookup(key); ok {
+		addr, err := netip.ParseAddr(v)
+		return addr, s.valueError(key, v, err)
+	} else {
+		return netip.Addr{}, s.noKey(key)
+	}
+}
+
+// IPNet get config CIDR value, such as "10.0.0.0/8".
+func (s *Section) IPNet(key string) (*net.IPNet, error) {
+	if v, ok := s.Lookup(key); ok {
+		n, err := parseIPNet(v)
+		return n, s.valueError(key, v, err)
+	} else {
+		return nil, s.noKey(key)
+	}
+}
+
+// Prefix get config CIDR value as netip.Prefix.
+func (s *Section) Prefix(key string) (netip.Prefix, error) {
+	if v, ok := s.Lookup(key); ok {
+		p, err := netip.ParsePrefix(v)
+		return p, s.valueError(key, v, err)
+	} else {
+		return netip.Prefix{}, s.noKey(key)
+	}
+}
+
+// HostPort get config "host:port" value, the host may be empty as in ":8080"
+// and the port must be in 1-65535.
+func (s *Section) HostPort(key string) (string, int, error) {
+	if v, ok := s.Lookup(key); ok {
+		host, port, err := parseHostPort(v)
+		return host, port, s.valueError(key, v, err)
+	} else {
+		return "", 0, s.noKey(key)
+	}
+}
+
+// URL get config absolute URL value, such as "http://127.0.0.1:8080/path".
+func (s *Section) URL(key string) (*url.URL, error) {
+	if v, ok := s.Lookup(key); ok {
+		u, err := parseURL(v)
+		return u, s.valueError(key, v, err)
+	} else {
+		return nil, s.noKey(key)
+	}
+}
+
+// TCPAddr get config "host:port" value resolved as a TCP address.
+func (s *Section) TCPAddr(key string) (*net.TCPAddr, error) {
+	if v, ok := s.Lookup(key); ok {
+		addr, err := parseTCPAddr(v)
+		return addr, s.valueError(key, v, err)
+	} else {
+		return nil, s.noKey(key)
+	}
+}
+
+func parseIP(v string) (net.IP, error) {
+	ip := net.ParseIP(v)
+	if ip == nil {
+		return nil, errors.New(fmt.Sprintf("invalid IP address: %q", v))
+	}
+	return ip, nil
+}
+
+func parseIPNet(v string) (*net.IPNet, error) {
+	_, ipnet, err := net.ParseCIDR(v)
+	return ipnet, err
+}
+
+func parseHostPort(v string) (string, int, error) {
+	host, port, err := net.SplitHostPort(v)
+	if err != nil {
+		return "", 0, err
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 1 || p > 65535 {
+		return "", 0, errors.New(fmt.Sprintf("invalid port: %q in address: %q", port, v))
+	}
+	return host, p, nil
+}
+
+func parseURL(v string) (*url.URL, error) {
+	u, err := url.Parse(v)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" {
+		return nil, errors.New(fmt.Sprintf("missing scheme in URL: %q", v))
+	}
+	return u, nil
+}
+
+func parseTCPAddr(v string) (*net.TCPAddr, error) {
+	if _, _, err := parseHostPort(v); err != nil {
+		return nil, err
+	}
+	return net.ResolveTCPAddr("tcp", v)
+}
@@ -0,0 +1,85 @@
+package goconf
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestNet(t *testing.T) {
+	c := parseString(t, `[net]
+ip 10.0.0.1
+ip6 ::1
+cidr 10.0.0.0/8
+acl 10.0.0.0/8,192.168.0.0/16
+listen :8080
+bad_port localhost:70000
+upstream http://127.0.0.1:8080/api
+relative /api
+addr 127.0.0.1:6379
+`)
+	s := c.Get("net")
+	if ip, err := s.IP("ip"); err != nil || !ip.Equal(net.IPv4(10, 0, 0, 1)) {
+		t.Errorf("s.IP(\"ip\") = %v, %v", ip, err)
+	}
+	if _, err := s.IP("cidr"); err == nil {
+		t.Errorf("s.IP(\"cidr\") no error")
+	}
+	if a, err := s.Addr("ip6"); err != nil || a != netip.IPv6Loopback() {
+		t.Errorf("s.Addr(\"ip6\") = %v, %v", a, err)
+	}
+	if n, err := s.IPNet("cidr"); err != nil || !n.Contains(net.IPv4(10, 1, 2, 3)) {
+		t.Errorf("s.IPNet(\"cidr\") = %v, %v", n, err)
+	}
+	if p, err := s.Prefix("cidr"); err != nil || p.Bits() != 8 {
+		t.Errorf("s.Prefix(\"cidr\") = %v, %v", p, err)
+	}
+	if host, port, err := s.HostPort("listen"); err != nil || host != "" || port != 8080 {
+		t.Errorf("s.HostPort(\"listen\") = %q, %d, %v", host, port, err)
+	}
+	if _, _, err := s.HostPort("bad_port"); err == nil {
+		t.Errorf("s.HostPort(\"bad_port\") no error")
+	}
+	if u, err := s.URL("upstream"); err != nil || u.Host != "127.0.0.1:8080" {
+		t.Errorf("s.URL(\"upstream\") = %v, %v", u, err)
+	}
+	if _, err := s.URL("relative"); err == nil {
+		t.Errorf("s.URL(\"relative\") no error")
+	}
+	if a, err := s.TCPAddr("addr"); err != nil || a.Port != 6379 {
+		t.Errorf("s.TCPAddr(\"addr\") = %v, %v", a, err)
+	}
+	var tc struct {
+		IP       net.IP         `goconf:"net:ip"`
+		Addr     netip.Addr     `goconf:"net:ip6"`
+		CIDR     *net.IPNet     `goconf:"net:cidr"`
+		ACL      []netip.Prefix `goconf:"net:acl:,"`
+		Upstream *url.URL       `goconf:"net:upstream"`
+	}
+	if err := c.Unmarshal(&tc); err != nil {
+		t.Fatalf("c.Unmarshal() failed (%s)", err.Error())
+	}
+	if !tc.IP.Equal(net.IPv4(10, 0, 0, 1)) || !tc.Addr.IsLoopback() || tc.CIDR == nil || len(tc.ACL) != 2 || tc.ACL[1].Bits() != 16 || tc.Upstream.Path != "/api" {
+		t.Errorf("c.Unmarshal() got %+v", tc)
+	}
+}
+
+func TestNetValueError(t *testing.T) {
+	s := parseString(t, "[net]\nbad x\n").Get("net")
+	for name, get := range map[string]func() error{
+		"IP":       func() error { _, err := s.IP("bad"); return err },
+		"Addr":     func() error { _, err := s.Addr("bad"); return err },
+		"IPNet":    func() error { _, err := s.IPNet("bad"); return err },
+		"Prefix":   func() error { _, err := s.Prefix("bad"); return err },
+		"HostPort": func() error { _, _, err := s.HostPort("bad"); return err },
+		"URL":      func() error { _, err := s.URL("bad"); return err },
+		"TCPAddr":  func() error { _, err := s.TCPAddr("bad"); return err },
+	} {
+		var verr *ValueError
+		if err := get(); !errors.As(err, &verr) || verr.Section != "net" || verr.Key != "bad" || verr.Value != "x" {
+			t.Errorf("%s() error %v not a ValueError", name, err)
+		}
+	}
+}
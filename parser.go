@@ -0,0 +1,157 @@
+package goconf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// valueParser parse a raw config value into a value of its registered type,
+// c holds the parsing options and may be nil.
+type valueParser func(c *Config, v string) (reflect.Value, error)
+
+var (
+	parsersMu sync.RWMutex
+	parsers   = map[reflect.Type]valueParser{}
+)
+
+func init() {
+	RegisterParser(func(v string) (string, error) { return v, nil })
+	registerParser(reflect.TypeOf(false), func(c *Config, v string) (reflect.Value, error) {
+		b, err := parseBool(v, c.strictBool())
+		return reflect.ValueOf(b), err
+	})
+	for t, bits := range map[reflect.Type]int{
+		reflect.TypeOf(int(0)):    strconv.IntSize,
+		reflect.TypeOf(int8(0)):   8,
+		reflect.TypeOf(int16(0)):  16,
+		reflect.TypeOf(int32(0)):  32,
+		reflect.TypeOf(int64(0)):  64,
+		reflect.TypeOf(uint(0)):   strconv.IntSize,
+		reflect.TypeOf(uint8(0)):  8,
+		reflect.TypeOf(uint16(0)): 16,
+		reflect.TypeOf(uint32(0)): 32,
+		reflect.TypeOf(uint64(0)): 64,
+	} {
+		t, bits := t, bits
+		if isInt(t.Kind()) {
+			registerParser(t, func(c *Config, v string) (reflect.Value, error) {
+				d, err := strconv.ParseInt(v, c.intBase(), bits)
+				return reflect.ValueOf(d).Convert(t), err
+			})
+		} else {
+			registerParser(t, func(c *Config, v string) (reflect.Value, error) {
+				d, err := strconv.ParseUint(v, c.intBase(), bits)
+				return reflect.ValueOf(d).Convert(t), err
+			})
+		}
+	}
+	RegisterParser(func(v string) (float32, error) {
+		d, err := strconv.ParseFloat(v, 32)
+		return float32(d), err
+	})
+	RegisterParser(func(v string) (float64, error) {
+		return strconv.ParseFloat(v, 64)
+	})
+	RegisterParser(parseFileMode)
+	registerParser(reflect.TypeOf(time.Duration(0)), func(c *Config, v string) (reflect.Value, error) {
+		d, err := parseTime(v, c.durationUnit())
+		return reflect.ValueOf(d), err
+	})
+}
+
+// RegisterParser register fn as the parser of type T. The parser is used by
+// Get and by Unmarshal for struct fields, slice elements, map keys and map
+// values of type T. A later registration replaces an earlier one, including
+// the built-in parsers.
+func RegisterParser[T any](fn func(v string) (T, error)) {
+	registerParser(reflect.TypeOf((*T)(nil)).Elem(), func(c *Config, v string) (reflect.Value, error) {
+		d, err := fn(v)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(&d).Elem(), nil
+	})
+}
+
+// registerParser register the parser of type t.
+func registerParser(t reflect.Type, p valueParser) {
+	parsersMu.Lock()
+	parsers[t] = p
+	parsersMu.Unlock()
+	clearPlans()
+}
+
+// lookupParser get the registered parser of type t.
+func lookupParser(t reflect.Type) valueParser {
+	parsersMu.RLock()
+	p := parsers[t]
+	parsersMu.RUnlock()
+	return p
+}
+
+// parseValue parse v to a value of type t. Types without a parser of their
+// own, such as "type Level int", use the parser of their underlying kind.
+func parseValue(c *Config, t reflect.Type, v string) (reflect.Value, error) {
+	if p := lookupParser(t); p != nil {
+		return p(c, v)
+	}
+	if bt, ok := kindTypes[t.Kind()]; ok {
+		if p := lookupParser(bt); p != nil {
+			vv, err := p(c, v)
+			if err != nil {
+				return vv, err
+			}
+			return vv.Convert(t), nil
+		}
+	}
+	return reflect.Value{}, errors.New(fmt.Sprintf("unkown type: %s", t))
+}
+
+// kindTypes is the built-in type of every basic kind.
+var kindTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:    reflect.TypeOf(false),
+	reflect.Int:     reflect.TypeOf(int(0)),
+	reflect.Int8:    reflect.TypeOf(int8(0)),
+	reflect.Int16:   reflect.TypeOf(int16(0)),
+	reflect.Int32:   reflect.TypeOf(int32(0)),
+	reflect.Int64:   reflect.TypeOf(int64(0)),
+	reflect.Uint:    reflect.TypeOf(uint(0)),
+	reflect.Uint8:   reflect.TypeOf(uint8(0)),
+	reflect.Uint16:  reflect.TypeOf(uint16(0)),
+	reflect.Uint32:  reflect.TypeOf(uint32(0)),
+	reflect.Uint64:  reflect.TypeOf(uint64(0)),
+	reflect.Float32: reflect.TypeOf(float32(0)),
+	reflect.Float64: reflect.TypeOf(float64(0)),
+	reflect.String:  reflect.TypeOf(""),
+}
+
+// Get get config value of type T using the parser registered for T, see
+// RegisterParser.
+//
+//	port, err := goconf.Get[uint16](conf.Get("http"), "port")
+func Get[T any](s *Section, key string) (T, error) {
+	var d T
+	v, ok := s.Lookup(key)
+	if !ok {
+		return d, s.noKey(key)
+	}
+	vv, err := parseValue(s.config(), reflect.TypeOf((*T)(nil)).Elem(), v)
+	if err != nil {
+		return d, err
+	}
+	return vv.Interface().(T), nil
+}
+
+// isInt return true for the signed integer kinds.
+func isInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+// isUint return true for the unsigned integer kinds.
+func isUint(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
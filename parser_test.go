@@ -0,0 +1,53 @@
+package goconf
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type testLevel int
+
+func parseTestLevel(v string) (testLevel, error) {
+	switch v {
+	case "debug":
+		return 0, nil
+	case "info":
+		return 1, nil
+	case "error":
+		return 2, nil
+	}
+	return 0, errors.New("unknown level: " + v)
+}
+
+func TestGet(t *testing.T) {
+	RegisterParser(parseTestLevel)
+	c := parseString(t, "[core]\nport 8080\nsleep 10s\nlevel info\nlevels debug,error\nbig 70000\n")
+	core := c.Get("core")
+	if port, err := Get[uint16](core, "port"); err != nil || port != 8080 {
+		t.Errorf("Get[uint16](\"port\") = %d, %v not equals 8080", port, err)
+	}
+	if _, err := Get[uint16](core, "big"); err == nil {
+		t.Errorf("Get[uint16](\"big\") overflow not reported")
+	}
+	if sleep, err := Get[time.Duration](core, "sleep"); err != nil || sleep != 10*time.Second {
+		t.Errorf("Get[time.Duration](\"sleep\") = %v, %v not equals 10s", sleep, err)
+	}
+	if level, err := Get[testLevel](core, "level"); err != nil || level != 1 {
+		t.Errorf("Get[testLevel](\"level\") = %v, %v not equals 1", level, err)
+	}
+	if _, err := Get[string](core, "none"); err == nil {
+		t.Errorf("Get[string](\"none\") no error")
+	}
+	var tc struct {
+		Level  testLevel     `goconf:"core:level"`
+		Levels []testLevel   `goconf:"core:levels:,"`
+		Sleep  time.Duration `goconf:"core:sleep"`
+	}
+	if err := c.Unmarshal(&tc); err != nil {
+		t.Fatalf("c.Unmarshal() failed (%s)", err.Error())
+	}
+	if tc.Level != 1 || len(tc.Levels) != 2 || tc.Levels[1] != 2 || tc.Sleep != 10*time.Second {
+		t.Errorf("c.Unmarshal() got %+v", tc)
+	}
+}
@@ -0,0 +1,160 @@
+package goconf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// kinds of a struct field plan
+const (
+	fieldValue       = iota // a key
+	fieldEmbedded           // an embedded struct, flattened
+	fieldSection            // a struct mapped to a section
+	fieldSections           // a slice or map of structs mapped to prefixed sections
+	fieldSectionName        // a string set to the section name
+)
+
+// fieldPlan is the decoding of a struct field, resolved once from its tags.
+type fieldPlan struct {
+	index  int
+	kind   int
+	field  reflect.StructField
+	name   string // section of a top-level key, section or section suffix
+	key    string
+	format string
+	def    string
+	hasDef bool
+	rules  []validateRule
+	// parser is the registered parser of the field type, used directly when
+	// there is no tag option.
+	parser valueParser
+}
+
+// structPlan is the decoding of a struct type, the fields to decode in
+// order, or the error of a malformed tag.
+type structPlan struct {
+	fields []fieldPlan
+	err    error
+	gen    uint64 // plansGen when built
+}
+
+// planKey is a struct type, decoded at the top-level, where tags name their
+// section, or nested in a section.
+type planKey struct {
+	t      reflect.Type
+	nested bool
+}
+
+var (
+	plans sync.Map // planKey:*structPlan
+	// plansGen is the generation of the cached plans, a plan built in an
+	// earlier generation is stale.
+	plansGen uint64
+)
+
+// planOf return the cached plan of the struct type t.
+func planOf(t reflect.Type, nested bool) *structPlan {
+	k := planKey{t: t, nested: nested}
+	gen := atomic.LoadUint64(&plansGen)
+	if v, ok := plans.Load(k); ok {
+		if p := v.(*structPlan); p.gen == gen {
+			return p
+		}
+	}
+	// a parser registered while building makes the plan stale, it is built
+	// again next time
+	p := newPlan(t, nested)
+	p.gen = gen
+	plans.Store(k, p)
+	return p
+}
+
+// clearPlans make the cached plans stale, a new parser may change how a type
+// is decoded.
+func clearPlans() {
+	atomic.AddUint64(&plansGen, 1)
+}
+
+// newPlan resolve the tags of the fields of the struct type t.
+func newPlan(t reflect.Type, nested bool) *structPlan {
+	p := &structPlan{}
+	n := t.NumField()
+	// enum every struct field
+	for i := 0; i < n; i++ {
+		tf := t.Field(i)
+		fp := fieldPlan{index: i, field: tf}
+		tag := strings.TrimSuffix(tf.Tag.Get("goconf"), ",omitempty")
+		// if tag "-" ignore
+		if tag == "-" {
+			continue
+		}
+		// flatten embedded struct
+		if tf.Anonymous && tag == "" {
+			if ft := indirectType(tf.Type); ft.Kind() == reflect.Struct && !isValueType(ft) {
+				fp.kind = fieldEmbedded
+				p.fields = append(p.fields, fp)
+				continue
+			}
+		}
+		// if tag empty or unexported field ignore
+		if tag == "" || tag == "omitempty" || !tf.IsExported() {
+			continue
+		}
+		// section name injected into the field
+		if tag == ":section" {
+			if nested && tf.Type.Kind() == reflect.String {
+				fp.kind = fieldSectionName
+				p.fields = append(p.fields, fp)
+			}
+			continue
+		}
+		if !strings.Contains(tag, ":") {
+			fp.name = tag
+			// struct mapped to a section
+			if ft := indirectType(tf.Type); ft.Kind() == reflect.Struct && !isValueType(ft) {
+				fp.kind = fieldSection
+				p.fields = append(p.fields, fp)
+				continue
+			}
+			// slice or map of structs mapped to the prefixed sections
+			if isSectionsType(tf.Type) {
+				fp.kind = fieldSections
+				p.fields = append(p.fields, fp)
+				continue
+			}
+			fp.name = ""
+		}
+		if !nested {
+			tagArr := strings.SplitN(tag, ":", 3)
+			if len(tagArr) < 2 {
+				p.err = errors.New(fmt.Sprintf("error tag: %s, must be section:field:delim(optional)", tag))
+				return p
+			}
+			fp.name, fp.key = tagArr[0], tagArr[1]
+			if len(tagArr) == 3 {
+				fp.format = tagArr[2]
+			}
+		} else {
+			tagArr := strings.SplitN(tag, ":", 2)
+			fp.key = tagArr[0]
+			if len(tagArr) == 2 {
+				fp.format = tagArr[1]
+			}
+		}
+		fp.def, fp.hasDef = tf.Tag.Lookup("default")
+		if rules := tf.Tag.Get("validate"); rules != "" {
+			if fp.rules, p.err = parseRules(tf, rules); p.err != nil {
+				return p
+			}
+		}
+		if fp.format == "" {
+			fp.parser = lookupParser(tf.Type)
+		}
+		p.fields = append(p.fields, fp)
+	}
+	return p
+}
@@ -0,0 +1,136 @@
+package goconf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterParser(func(v string) (time.Time, error) { return time.Parse(time.RFC3339, v) })
+	RegisterParser(parseLocation)
+	RegisterParser(ParseTimeOfDay)
+}
+
+// timeLayouts is the time layout by name, usable in place of a layout.
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RubyDate":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// timeLayout return the layout for a layout name such as "RFC3339" or
+// "DateOnly", "" means RFC3339, any other value is a layout itself.
+func timeLayout(layout string) string {
+	if layout == "" {
+		return time.RFC3339
+	}
+	if l, ok := timeLayouts[layout]; ok {
+		return l
+	}
+	return layout
+}
+
+// Time get config time.Time value in the specified layout, or a layout name
+// such as "DateTime", "" means RFC3339.
+func (s *Section) Time(key, layout string) (time.Time, error) {
+	if v, ok := s.Lookup(key); ok {
+		t, err := time.Parse(timeLayout(layout), v)
+		return t, s.valueError(key, v, err)
+	} else {
+		return time.Time{}, s.noKey(key)
+	}
+}
+
+// Location get config time zone value by IANA name, such as "Asia/Shanghai"
+// or "UTC".
+func (s *Section) Location(key string) (*time.Location, error) {
+	if v, ok := s.Lookup(key); ok {
+		loc, err := parseLocation(v)
+		return loc, s.valueError(key, v, err)
+	} else {
+		return nil, s.noKey(key)
+	}
+}
+
+// TimeOfDay get config wall clock value, such as "02:30" or "23:59:59".
+func (s *Section) TimeOfDay(key string) (TimeOfDay, error) {
+	if v, ok := s.Lookup(key); ok {
+		t, err := ParseTimeOfDay(v)
+		return t, s.valueError(key, v, err)
+	} else {
+		return TimeOfDay{}, s.noKey(key)
+	}
+}
+
+func parseLocation(v string) (*time.Location, error) {
+	if v == "" {
+		return nil, errors.New("empty time zone")
+	}
+	return time.LoadLocation(v)
+}
+
+// TimeOfDay is a wall clock time without a date, such as the start of a
+// daily maintenance window.
+type TimeOfDay struct {
+	Hour   int
+	Minute int
+	Second int
+}
+
+// ParseTimeOfDay parse "15:04" or "15:04:05" in 24-hour clock.
+func ParseTimeOfDay(v string) (TimeOfDay, error) {
+	layout := "15:04"
+	if strings.Count(v, ":") == 2 {
+		layout = "15:04:05"
+	}
+	t, err := time.Parse(layout, strings.TrimSpace(v))
+	if err != nil {
+		return TimeOfDay{}, errors.New(fmt.Sprintf("invalid time of day: %q, must be hh:mm or hh:mm:ss", v))
+	}
+	return TimeOfDay{Hour: t.Hour(), Minute: t.Minute(), Second: t.Second()}, nil
+}
+
+// String return the time as "15:04", or "15:04:05" if it has seconds.
+func (t TimeOfDay) String() string {
+	if t.Second != 0 {
+		return fmt.Sprintf("%02d:%02d:%02d", t.Hour, t.Minute, t.Second)
+	}
+	return fmt.Sprintf("%02d:%02d", t.Hour, t.Minute)
+}
+
+// Duration return the time elapsed since midnight.
+func (t TimeOfDay) Duration() time.Duration {
+	return time.Duration(t.Hour)*time.Hour + time.Duration(t.Minute)*time.Minute + time.Duration(t.Second)*time.Second
+}
+
+// On return the time on the date of day, in the location of day.
+func (t TimeOfDay) On(day time.Time) time.Time {
+	y, m, d := day.Date()
+	return time.Date(y, m, d, t.Hour, t.Minute, t.Second, 0, day.Location())
+}
+
+// Next return the first occurrence of the time at or after now, in the
+// location of now.
+func (t TimeOfDay) Next(now time.Time) time.Time {
+	next := t.On(now)
+	if next.Before(now) {
+		next = t.On(now.AddDate(0, 0, 1))
+	}
+	return next
+}
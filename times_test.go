@@ -0,0 +1,72 @@
+package goconf
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimes(t *testing.T) {
+	c := parseString(t, `[report]
+at 2024-01-02T03:04:05Z
+day 2024-01-02
+zone Asia/Shanghai
+bad_zone Mars/Olympus
+window 02:30
+exact 23:59:59
+bad 24:00
+bad1 2:30x
+`)
+	s := c.Get("report")
+	if at, err := s.Time("at", ""); err != nil || at.Unix() != 1704164645 {
+		t.Errorf("s.Time(\"at\") = %v, %v", at, err)
+	}
+	if day, err := s.Time("day", "DateOnly"); err != nil || day.Day() != 2 {
+		t.Errorf("s.Time(\"day\") = %v, %v", day, err)
+	}
+	var verr *ValueError
+	if _, err := s.Time("day", ""); !errors.As(err, &verr) || verr.Section != "report" || verr.Key != "day" {
+		t.Errorf("s.Time(\"day\") error %v not a ValueError", err)
+	}
+	if loc, err := s.Location("zone"); err != nil || loc.String() != "Asia/Shanghai" {
+		t.Errorf("s.Location(\"zone\") = %v, %v", loc, err)
+	}
+	if _, err := s.Location("bad_zone"); !errors.As(err, &verr) {
+		t.Errorf("s.Location(\"bad_zone\") error %v not a ValueError", err)
+	}
+	w, err := s.TimeOfDay("window")
+	if err != nil || w.Hour != 2 || w.Minute != 30 || w.String() != "02:30" {
+		t.Errorf("s.TimeOfDay(\"window\") = %v, %v", w, err)
+	}
+	if e, err := s.TimeOfDay("exact"); err != nil || e.Duration() != 24*time.Hour-time.Second {
+		t.Errorf("s.TimeOfDay(\"exact\") = %v, %v", e, err)
+	}
+	for _, k := range []string{"bad", "bad1", "zone"} {
+		if _, err := s.TimeOfDay(k); err == nil {
+			t.Errorf("s.TimeOfDay(%q) no error", k)
+		}
+	}
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	if next := w.Next(now); !next.Equal(time.Date(2024, 1, 3, 2, 30, 0, 0, time.UTC)) {
+		t.Errorf("w.Next() = %v", next)
+	}
+	var tc struct {
+		At     time.Time      `goconf:"report:at"`
+		Day    time.Time      `goconf:"report:day:2006-01-02"`
+		Zone   *time.Location `goconf:"report:zone"`
+		Window TimeOfDay      `goconf:"report:window"`
+	}
+	if err := c.Unmarshal(&tc); err != nil {
+		t.Fatalf("c.Unmarshal() failed (%s)", err.Error())
+	}
+	if tc.At.Unix() != 1704164645 || tc.Day.Day() != 2 || tc.Zone == nil || tc.Window != w {
+		t.Errorf("c.Unmarshal() got %+v", tc)
+	}
+	var bad struct {
+		Day time.Time `goconf:"report:day:Kitchen"`
+	}
+	var uerr *UnmarshalError
+	if err := c.Unmarshal(&bad); !errors.As(err, &uerr) || uerr.Key != "day" {
+		t.Errorf("c.Unmarshal() error %v not an UnmarshalError", err)
+	}
+}
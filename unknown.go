@@ -0,0 +1,106 @@
+package goconf
+
+import (
+	"fmt"
+	"sort"
+)
+
+// An UnknownError describes a section, or a key of a section, that no
+// struct field asks for, see Config.DisallowUnknown.
+type UnknownError struct {
+	Section string
+	Key     string // "" for an unknown section
+	Line    int    // line in the parsed file, 0 if unknown
+	// Suggestion is the closest known section or key, "" if none is close.
+	Suggestion string
+}
+
+func (e *UnknownError) Error() string {
+	if e.Key == "" {
+		msg := fmt.Sprintf("unknown section: [%s]", e.Section)
+		if e.Suggestion != "" {
+			msg += fmt.Sprintf(", did you mean [%s]?", e.Suggestion)
+		}
+		return msg
+	}
+	msg := fmt.Sprintf("unknown key: \"%s\" in [%s]", e.Key, e.Section)
+	if e.Line > 0 {
+		msg += fmt.Sprintf(" at %d", e.Line)
+	}
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(", did you mean \"%s\"?", e.Suggestion)
+	}
+	return msg
+}
+
+// unknown add an UnknownError to d.errs for every section and key of c not
+// known by d, in file order.
+func (c *Config) unknown(d *decodeState) {
+	sections := make([]string, 0, len(d.known))
+	for name := range d.known {
+		sections = append(sections, name)
+	}
+	sort.Strings(sections)
+	for _, name := range c.dataOrder {
+		keys, ok := d.known[name]
+		if !ok {
+			d.errs = append(d.errs, &UnknownError{Section: name, Suggestion: suggest(name, sections)})
+			continue
+		}
+		known := make([]string, 0, len(keys))
+		for k := range keys {
+			known = append(known, k)
+		}
+		sort.Strings(known)
+		s := c.data[name]
+		for _, k := range s.dataOrder {
+			if _, ok := s.data[k]; !ok || keys[k] {
+				continue
+			}
+			d.errs = append(d.errs, &UnknownError{Section: name, Key: k, Line: s.Line(k), Suggestion: suggest(k, known)})
+		}
+	}
+}
+
+// suggest return the word of words closest to w by edit distance, "" if none
+// is within a third of the length of w plus one.
+func suggest(w string, words []string) string {
+	best, min := "", len(w)/3+2
+	for _, word := range words {
+		if d := levenshtein(w, word); d < min {
+			best, min = word, d
+		}
+	}
+	return best
+}
+
+// levenshtein return the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a int, bs ...int) int {
+	for _, b := range bs {
+		if b < a {
+			a = b
+		}
+	}
+	return a
+}
@@ -0,0 +1,58 @@
+package goconf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDisallowUnknown(t *testing.T) {
+	c := parseString(t, `[core]
+id 1
+tiemout 1s
+zzz 1
+[redsi]
+addr 127.0.0.1
+[pool.a]
+addr 10.0.0.1
+`)
+	var tc struct {
+		ID      int                      `goconf:"core:id"`
+		Timeout string                   `goconf:"core:timeout"`
+		Redis   *testRedis               `goconf:"redis"`
+		Pools   map[string]*testUpstream `goconf:"pool"`
+	}
+	if err := c.Unmarshal(&tc); err != nil {
+		t.Fatalf("c.Unmarshal() failed (%s)", err.Error())
+	}
+	c.DisallowUnknown = true
+	err := c.Unmarshal(&tc)
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("c.Unmarshal() errors:\n%v", err)
+	}
+	want := []UnknownError{
+		{Section: "core", Key: "tiemout", Line: 3, Suggestion: "timeout"},
+		{Section: "core", Key: "zzz", Line: 4},
+		{Section: "redsi", Suggestion: "redis"},
+	}
+	for i, e := range errs {
+		var uerr *UnknownError
+		if !errors.As(e, &uerr) || *uerr != want[i] {
+			t.Errorf("error %d = %v, want %+v", i, e, want[i])
+		}
+	}
+	if s := errs[0].Error(); s != `unknown key: "tiemout" in [core] at 3, did you mean "timeout"?` {
+		t.Errorf("UnknownError = %s", s)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		d    int
+	}{{"", "", 0}, {"abc", "", 3}, {"kitten", "sitting", 3}, {"tiemout", "timeout", 2}} {
+		if d := levenshtein(tt.a, tt.b); d != tt.d {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, d, tt.d)
+		}
+	}
+}
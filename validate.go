@@ -0,0 +1,216 @@
+package goconf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A ValidationError describes a struct field whose config value fails a rule
+// of its "validate" tag, see Unmarshal.
+type ValidationError struct {
+	Field   string // field path, such as "Redis.Pool.Size"
+	Section string
+	Key     string
+	Value   string
+	Line    int // line in the parsed file, 0 if unknown
+	Rule    string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Rule == "required" {
+		return fmt.Sprintf("key: \"%s\" in [%s] of field: %s is required", e.Key, e.Section, e.Field)
+	}
+	line := ""
+	if e.Line > 0 {
+		line = fmt.Sprintf(" at %d", e.Line)
+	}
+	return fmt.Sprintf("key: \"%s\" in [%s]%s of field: %s value %q fails rule: %s", e.Key, e.Section, line, e.Field, e.Value, e.Rule)
+}
+
+// Errors is a list of errors reported together.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap return the errors, for errors.Is and errors.As.
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// err return e, or nil if e is empty.
+func (e Errors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// validateRule is a rule of a validate tag, parsed once by the plan of
+// its struct.
+type validateRule struct {
+	text  string   // the rule as written
+	name  string   // "required", "min", "max", "oneof" or "pattern"
+	arg   string   // the bound of min and max
+	words []string // the words of oneof
+	re    *regexp.Regexp
+	// n is the bound of min and max for strings, slices and maps, compared
+	// to their length, hasN is false for a bound decoded as the field.
+	n    int
+	hasN bool
+}
+
+// parseRules parse the validate tag of the struct field tf. The pattern of
+// a "pattern=" rule takes the rest of the tag.
+func parseRules(tf reflect.StructField, rules string) ([]validateRule, error) {
+	var vrs []validateRule
+	for rules != "" {
+		var rule string
+		if strings.HasPrefix(rules, "pattern=") {
+			rule, rules = rules, ""
+		} else if i := strings.IndexByte(rules, ','); i >= 0 {
+			rule, rules = rules[:i], rules[i+1:]
+		} else {
+			rule, rules = rules, ""
+		}
+		name, arg, _ := strings.Cut(rule, "=")
+		vr := validateRule{text: rule, name: name, arg: arg}
+		switch name {
+		case "required":
+		case "min", "max", "oneof", "pattern":
+			if arg == "" {
+				return nil, errors.New(fmt.Sprintf("error validate rule: %s in struct field: %s, needs a value", rule, tf.Name))
+			}
+		default:
+			return nil, errors.New(fmt.Sprintf("unknown validate rule: %s in struct field: %s (support rules: \"required\", \"min\", \"max\", \"oneof\", \"pattern\")", rule, tf.Name))
+		}
+		switch name {
+		case "min", "max":
+			switch indirectType(tf.Type).Kind() {
+			case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+				n, err := strconv.Atoi(arg)
+				if err != nil {
+					return nil, errors.New(fmt.Sprintf("error validate rule: %s in struct field: %s, %s", rule, tf.Name, err))
+				}
+				vr.n, vr.hasN = n, true
+			}
+		case "oneof":
+			vr.words = strings.Split(arg, "|")
+		case "pattern":
+			re, err := regexp.Compile(arg)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("error validate rule: %s in struct field: %s, %s", rule, tf.Name, err))
+			}
+			vr.re = re
+		}
+		vrs = append(vrs, vr)
+	}
+	return vrs, nil
+}
+
+// validate check the rules of field vf at path, decoded from value if ok,
+// and add the failed ones to errs. A min or max bound that cannot be decoded
+// as the field is returned as an error.
+func (c *Config) validate(s *Section, section, key, format, value string, ok bool, vf reflect.Value, tf reflect.StructField, path string, rules []validateRule, errs *Errors) error {
+	fail := func(rule string) {
+		*errs = append(*errs, &ValidationError{Field: path, Section: section, Key: key, Value: value, Line: s.Line(key), Rule: rule})
+	}
+	for i := range rules {
+		vr := &rules[i]
+		if vr.name == "required" {
+			if !ok {
+				fail(vr.text)
+			}
+			continue
+		}
+		if !ok {
+			// nothing to check
+			continue
+		}
+		switch vr.name {
+		case "min", "max":
+			cmp, err := c.compare(s, key, format, vr, vf, tf)
+			if err != nil {
+				return errors.New(fmt.Sprintf("error validate rule: %s in struct field: %s, %s", vr.text, tf.Name, err))
+			}
+			if vr.name == "min" && cmp < 0 || vr.name == "max" && cmp > 0 {
+				fail(vr.text)
+			}
+		case "oneof":
+			found := false
+			for _, w := range vr.words {
+				if w == value {
+					found = true
+					break
+				}
+			}
+			if !found {
+				fail(vr.text)
+			}
+		case "pattern":
+			if !vr.re.MatchString(value) {
+				fail(vr.text)
+			}
+		}
+	}
+	return nil
+}
+
+// compare return -1, 0 or 1 as the decoded field vf is less than, equal to or
+// greater than the bound of vr. The length of strings, slices and maps is
+// compared, other bounds are decoded as the field, as they depend on the
+// options of c.
+func (c *Config) compare(s *Section, key, format string, vr *validateRule, vf reflect.Value, tf reflect.StructField) (int, error) {
+	for vf.Kind() == reflect.Ptr {
+		if vf.IsNil() {
+			return 0, nil
+		}
+		vf = vf.Elem()
+	}
+	if vr.hasN {
+		return compareInt(int64(vf.Len()), int64(vr.n)), nil
+	}
+	bv := reflect.New(vf.Type()).Elem()
+	if err := c.decodeField(s, key, format, vr.arg, bv, tf); err != nil {
+		return 0, err
+	}
+	switch {
+	case isInt(vf.Kind()):
+		return compareInt(vf.Int(), bv.Int()), nil
+	case isUint(vf.Kind()):
+		a, b := vf.Uint(), bv.Uint()
+		if a < b {
+			return -1, nil
+		} else if a > b {
+			return 1, nil
+		}
+		return 0, nil
+	case vf.Kind() == reflect.Float32 || vf.Kind() == reflect.Float64:
+		a, b := vf.Float(), bv.Float()
+		if a < b {
+			return -1, nil
+		} else if a > b {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, errors.New(fmt.Sprintf("cannot compare type: %s", vf.Type()))
+}
+
+func compareInt(a, b int64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package goconf
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	c := parseString(t, `[core]
+port 0
+level trace
+name a-b
+hosts a,b,c
+buf 2MiB
+`)
+	var tc struct {
+		Timeout time.Duration `goconf:"core:timeout:time" default:"30s" validate:"min=1s"`
+		Retry   *int          `goconf:"none:retry" default:"3"`
+		Port    int           `goconf:"core:port" validate:"required,min=1,max=65535"`
+		Level   string        `goconf:"core:level" validate:"oneof=debug|info|error"`
+		Name    string        `goconf:"core:name" validate:"max=8,pattern=^[a-z,]+$"`
+		Hosts   []string      `goconf:"core:hosts" validate:"max=2"`
+		Buf     int64         `goconf:"core:buf:memory" validate:"max=1MiB"`
+		Addr    string        `goconf:"core:addr" validate:"required"`
+		Ratio   float64       `goconf:"core:ratio" validate:"min=0.5"`
+	}
+	err := c.Unmarshal(&tc)
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("c.Unmarshal() error %v not Errors", err)
+	}
+	want := []string{"min=1", "oneof=debug|info|error", "pattern=^[a-z,]+$", "max=2", "max=1MiB", "required"}
+	if len(errs) != len(want) {
+		t.Fatalf("c.Unmarshal() errors:\n%v", err)
+	}
+	for i, e := range errs {
+		var verr *ValidationError
+		if !errors.As(e, &verr) || verr.Rule != want[i] {
+			t.Errorf("error %d = %v, want rule %s", i, e, want[i])
+		}
+	}
+	if tc.Timeout != 30*time.Second || tc.Retry == nil || *tc.Retry != 3 {
+		t.Errorf("Timeout %v, Retry %v", tc.Timeout, tc.Retry)
+	}
+	var bad struct {
+		Port int `goconf:"core:port" validate:"between=1"`
+	}
+	if err = c.Unmarshal(&bad); err == nil || errors.As(err, &errs) {
+		t.Errorf("c.Unmarshal() unknown rule error = %v", err)
+	}
+	var badPattern struct {
+		Name string `goconf:"core:name" validate:"pattern=("`
+	}
+	if err = c.Unmarshal(&badPattern); err == nil || errors.As(err, &errs) {
+		t.Errorf("c.Unmarshal() invalid pattern error = %v", err)
+	}
+}
+
+func TestValidateMissingSection(t *testing.T) {
+	c := parseString(t, "[core]\nid 1\n")
+	var tc struct {
+		Redis struct {
+			Addr    string        `goconf:"addr" validate:"required"`
+			Timeout time.Duration `goconf:"timeout" default:"30s"`
+		} `goconf:"redis"`
+		Cache *struct {
+			Addr string `goconf:"addr" validate:"required"`
+		} `goconf:"cache"`
+	}
+	err := c.Unmarshal(&tc)
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("c.Unmarshal() errors:\n%v", err)
+	}
+	var verr *ValidationError
+	if !errors.As(errs[0], &verr) || verr.Rule != "required" || verr.Field != "Redis.Addr" || verr.Section != "redis" {
+		t.Errorf("error %v not Redis.Addr required", errs[0])
+	}
+	if tc.Redis.Timeout != 30*time.Second {
+		t.Errorf("Redis.Timeout %v not equals 30s", tc.Redis.Timeout)
+	}
+	if tc.Cache != nil {
+		t.Errorf("Cache allocated without section")
+	}
+}